@@ -0,0 +1,242 @@
+package apikey
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Faze-Technologies/go-utils/logs"
+	"github.com/Faze-Technologies/go-utils/request"
+	"github.com/goccy/go-json"
+)
+
+// memoryCache is an in-memory keyCache. TTLs are ignored.
+type memoryCache struct {
+	mu     sync.Mutex
+	values map[string]string
+	sets   map[string]map[string]bool
+	// saddErr, when set, fails SAdd.
+	saddErr error
+}
+
+func newMemoryCache() *memoryCache {
+	return &memoryCache{values: map[string]string{}, sets: map[string]map[string]bool{}}
+}
+
+func (m *memoryCache) Get(_ context.Context, key string) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	v, ok := m.values[key]
+	if !ok {
+		return "", errors.New(string(request.KeyNotFoundError))
+	}
+	return v, nil
+}
+
+func (m *memoryCache) Set(_ context.Context, key string, value string, _ time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.values[key] = value
+	return nil
+}
+
+func (m *memoryCache) SetJson(ctx context.Context, key string, value interface{}, ttl time.Duration) error {
+	b, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	return m.Set(ctx, key, string(b), ttl)
+}
+
+func (m *memoryCache) Delete(_ context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.values, key)
+	return nil
+}
+
+func (m *memoryCache) add(key string, delta int64) int64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	n, _ := strconv.ParseInt(m.values[key], 10, 64)
+	n += delta
+	m.values[key] = strconv.FormatInt(n, 10)
+	return n
+}
+
+func (m *memoryCache) Incr(_ context.Context, key string, _ time.Duration) (int64, error) {
+	return m.add(key, 1), nil
+}
+
+func (m *memoryCache) Decr(_ context.Context, key string, _ time.Duration) (int64, error) {
+	return m.add(key, -1), nil
+}
+
+func (m *memoryCache) SAdd(_ context.Context, key string, members ...interface{}) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.saddErr != nil {
+		return 0, m.saddErr
+	}
+	if m.sets[key] == nil {
+		m.sets[key] = map[string]bool{}
+	}
+	var added int64
+	for _, member := range members {
+		s := member.(string)
+		if !m.sets[key][s] {
+			m.sets[key][s] = true
+			added++
+		}
+	}
+	return added, nil
+}
+
+func (m *memoryCache) SMembers(_ context.Context, key string) ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var out []string
+	for member := range m.sets[key] {
+		out = append(out, member)
+	}
+	return out, nil
+}
+
+func (m *memoryCache) SetTTL(context.Context, string, time.Duration) (bool, error) {
+	return true, nil
+}
+
+func (m *memoryCache) GetMultiKeys(_ context.Context, keys []string) (map[string]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	out := make(map[string]string, len(keys))
+	for _, k := range keys {
+		out[k] = m.values[k]
+	}
+	return out, nil
+}
+
+func newTestService(store Store) *Service {
+	logs.NewLogger()
+	s := NewService(store, nil, 0, 0)
+	s.cache = newMemoryCache()
+	return s
+}
+
+func TestAuthenticate(t *testing.T) {
+	expired := time.Now().Add(-time.Hour)
+	keys := map[string]*Key{
+		HashKey("live"):     {Id: "k1", PartnerId: "p1"},
+		HashKey("disabled"): {Id: "k2", PartnerId: "p1", Disabled: true},
+		HashKey("expired"):  {Id: "k3", PartnerId: "p1", ExpiresAt: &expired},
+	}
+	lookups := 0
+	s := newTestService(StoreFunc(func(_ context.Context, hash string) (*Key, error) {
+		lookups++
+		if k, ok := keys[hash]; ok {
+			return k, nil
+		}
+		return nil, ErrKeyNotFound
+	}))
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		if k, err := s.Authenticate(ctx, "live"); err != nil || k.Id != "k1" {
+			t.Fatalf("Authenticate(live) = %v, %v", k, err)
+		}
+		if _, err := s.Authenticate(ctx, "unknown"); !errors.Is(err, ErrKeyNotFound) {
+			t.Errorf("Authenticate(unknown) = %v, want %v", err, ErrKeyNotFound)
+		}
+	}
+	if lookups != 2 {
+		t.Errorf("store looked up %d times, want 2 (hits and misses cached)", lookups)
+	}
+	for _, raw := range []string{"disabled", "expired"} {
+		if _, err := s.Authenticate(ctx, raw); !errors.Is(err, ErrKeyInactive) {
+			t.Errorf("Authenticate(%s) = %v, want %v", raw, err, ErrKeyInactive)
+		}
+	}
+}
+
+func TestConsumeQuota(t *testing.T) {
+	s := newTestService(nil)
+	ctx := context.Background()
+	key := &Key{Id: "k1", PartnerId: "p1", DailyQuota: 3}
+
+	for i := 0; i < 3; i++ {
+		if err := s.Consume(ctx, key); err != nil {
+			t.Fatalf("request %d: Consume() = %v", i, err)
+		}
+	}
+	for i := 0; i < 5; i++ {
+		if err := s.Consume(ctx, key); !errors.Is(err, ErrDailyQuotaExceeded) {
+			t.Fatalf("over quota: Consume() = %v, want %v", err, ErrDailyQuotaExceeded)
+		}
+	}
+	if n, err := s.DailyUsage(ctx, "k1", time.Now()); err != nil || n != 3 {
+		t.Errorf("DailyUsage() = %d, %v, want 3 (rejected requests not counted)", n, err)
+	}
+
+	monthly := &Key{Id: "k2", PartnerId: "p2", MonthlyQuota: 1}
+	if err := s.Consume(ctx, monthly); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Consume(ctx, monthly); !errors.Is(err, ErrMonthlyQuotaExceeded) {
+		t.Errorf("Consume() = %v, want %v", err, ErrMonthlyQuotaExceeded)
+	}
+}
+
+func TestConsumeTrackingFailure(t *testing.T) {
+	s := newTestService(nil)
+	ctx := context.Background()
+	cache := s.cache.(*memoryCache)
+	cache.saddErr = errors.New("redis down")
+
+	key := &Key{Id: "k1", PartnerId: "p1"}
+	if err := s.Consume(ctx, key); err != nil {
+		t.Fatalf("Consume() = %v, want the served request allowed", err)
+	}
+	cache.saddErr = nil
+	if err := s.Consume(ctx, key); err != nil {
+		t.Fatal(err)
+	}
+
+	usage, err := s.ExportMonthlyUsage(ctx, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(usage) != 1 || usage[0].Count != 2 {
+		t.Errorf("ExportMonthlyUsage() = %+v, want both requests billed once tracking recovers", usage)
+	}
+}
+
+func TestExportMonthlyUsage(t *testing.T) {
+	s := newTestService(nil)
+	ctx := context.Background()
+	for _, k := range []*Key{{Id: "b", PartnerId: "p2"}, {Id: "a", PartnerId: "p1"}, {Id: "b", PartnerId: "p2"}} {
+		if err := s.Consume(ctx, k); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	usage, err := s.ExportMonthlyUsage(ctx, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	period := monthPeriod(time.Now())
+	want := []Usage{
+		{KeyId: "a", PartnerId: "p1", Period: period, Count: 1},
+		{KeyId: "b", PartnerId: "p2", Period: period, Count: 2},
+	}
+	if len(usage) != len(want) {
+		t.Fatalf("ExportMonthlyUsage() = %+v, want %+v", usage, want)
+	}
+	for i := range want {
+		if usage[i] != want[i] {
+			t.Errorf("ExportMonthlyUsage()[%d] = %+v, want %+v", i, usage[i], want[i])
+		}
+	}
+}
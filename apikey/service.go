@@ -0,0 +1,121 @@
+package apikey
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Faze-Technologies/go-utils/cache"
+	"github.com/Faze-Technologies/go-utils/logs"
+	"github.com/goccy/go-json"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultCacheTTL         = 5 * time.Minute
+	defaultNegativeCacheTTL = 30 * time.Second
+	// Monthly counters outlive the month so billing can export them after
+	// it closes.
+	monthlyUsageRetention = 100 * 24 * time.Hour
+	dailyUsageRetention   = 48 * time.Hour
+	notFoundMarker        = "-"
+)
+
+var (
+	ErrKeyInactive          = errors.New("apikey: key disabled or expired")
+	ErrDailyQuotaExceeded   = errors.New("apikey: daily quota exceeded")
+	ErrMonthlyQuotaExceeded = errors.New("apikey: monthly quota exceeded")
+)
+
+// keyCache is the Redis surface the Service uses; *cache.Cache satisfies it.
+type keyCache interface {
+	Get(ctx context.Context, key string) (string, error)
+	Set(ctx context.Context, key string, value string, expiration time.Duration) error
+	SetJson(ctx context.Context, key string, value interface{}, expiration time.Duration) error
+	Delete(ctx context.Context, key string) error
+	Incr(ctx context.Context, key string, expiration time.Duration) (int64, error)
+	Decr(ctx context.Context, key string, expiration time.Duration) (int64, error)
+	SAdd(ctx context.Context, key string, members ...interface{}) (int64, error)
+	SMembers(ctx context.Context, key string) ([]string, error)
+	SetTTL(ctx context.Context, key string, expiration time.Duration) (bool, error)
+	GetMultiKeys(ctx context.Context, keys []string) (map[string]string, error)
+}
+
+// Service is the package handle returned by NewService.
+type Service struct {
+	store  Store
+	cache  keyCache
+	ttl    time.Duration
+	negTTL time.Duration
+}
+
+// NewService puts a Redis cache in front of store. Zero TTLs use the
+// package defaults.
+func NewService(store Store, c *cache.Cache, cacheTTL, negativeCacheTTL time.Duration) *Service {
+	if cacheTTL <= 0 {
+		cacheTTL = defaultCacheTTL
+	}
+	if negativeCacheTTL <= 0 {
+		negativeCacheTTL = defaultNegativeCacheTTL
+	}
+	return &Service{store: store, cache: c, ttl: cacheTTL, negTTL: negativeCacheTTL}
+}
+
+func keyCacheKey(hash string) string {
+	return fmt.Sprintf("apikey:hash:%s", hash)
+}
+
+// Authenticate hashes rawKey and resolves it to an active Key.
+func (s *Service) Authenticate(ctx context.Context, rawKey string) (*Key, error) {
+	key, err := s.lookup(ctx, HashKey(rawKey))
+	if err != nil {
+		return nil, err
+	}
+	if !key.Active(time.Now()) {
+		return nil, ErrKeyInactive
+	}
+	return key, nil
+}
+
+func (s *Service) lookup(ctx context.Context, hash string) (*Key, error) {
+	logger := logs.WithContext(ctx)
+	cacheKey := keyCacheKey(hash)
+
+	if cached, err := s.cache.Get(ctx, cacheKey); err == nil {
+		if cached == notFoundMarker {
+			return nil, ErrKeyNotFound
+		}
+		var key Key
+		if err := json.Unmarshal([]byte(cached), &key); err == nil {
+			return &key, nil
+		}
+		logger.Warn("Discarding unreadable cached API key", zap.Error(err))
+	}
+
+	key, err := s.store.LookupByHash(ctx, hash)
+	if errors.Is(err, ErrKeyNotFound) {
+		// Cache misses too so a flood of bad keys doesn't reach the store.
+		if cErr := s.cache.Set(ctx, cacheKey, notFoundMarker, s.negTTL); cErr != nil {
+			logger.Error("Error caching API key miss", zap.Error(cErr))
+		}
+		return nil, ErrKeyNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("apikey: store lookup: %w", err)
+	}
+
+	if err := s.cache.SetJson(ctx, cacheKey, key, s.ttl); err != nil {
+		logger.Error("Error caching API key", zap.Error(err))
+	}
+	return key, nil
+}
+
+// Invalidate drops a cached key, e.g. after it is revoked or its quota changes.
+func (s *Service) Invalidate(ctx context.Context, rawKeyOrHash string, isHash bool) error {
+	hash := rawKeyOrHash
+	if !isHash {
+		hash = HashKey(rawKeyOrHash)
+	}
+	return s.cache.Delete(ctx, keyCacheKey(hash))
+}
@@ -0,0 +1,67 @@
+// Package apikey authenticates partner API keys. Keys are only ever handled
+// as SHA-256 hashes: the raw key is hashed on arrival and looked up through a
+// pluggable Store (typically the partner service's database), with a Redis
+// cache in front. Per-key daily and monthly usage is counted in Redis, which
+// both enforces quotas and feeds the billing export.
+package apikey
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/Faze-Technologies/go-utils/utils"
+)
+
+// ErrKeyNotFound is returned by a Store when no key matches the hash.
+var ErrKeyNotFound = errors.New("apikey: key not found")
+
+// Key is the stored record for an issued API key. Quotas of zero mean
+// unlimited.
+type Key struct {
+	Id           string     `json:"id"`
+	PartnerId    string     `json:"partnerId"`
+	PartnerName  string     `json:"partnerName"`
+	Scopes       []string   `json:"scopes"`
+	DailyQuota   int64      `json:"dailyQuota"`
+	MonthlyQuota int64      `json:"monthlyQuota"`
+	Disabled     bool       `json:"disabled"`
+	ExpiresAt    *time.Time `json:"expiresAt,omitempty"`
+}
+
+// Active reports whether the key may be used at now.
+func (k *Key) Active(now time.Time) bool {
+	if k.Disabled {
+		return false
+	}
+	return k.ExpiresAt == nil || now.Before(*k.ExpiresAt)
+}
+
+// HasScope reports whether the key was granted scope.
+func (k *Key) HasScope(scope string) bool {
+	for _, s := range k.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// Store resolves a key hash to its record. Implementations return
+// ErrKeyNotFound for unknown hashes.
+type Store interface {
+	LookupByHash(ctx context.Context, hash string) (*Key, error)
+}
+
+// StoreFunc adapts a function to the Store interface.
+type StoreFunc func(ctx context.Context, hash string) (*Key, error)
+
+func (f StoreFunc) LookupByHash(ctx context.Context, hash string) (*Key, error) {
+	return f(ctx, hash)
+}
+
+// HashKey returns the hex SHA-256 of a raw API key — the only form a key
+// should be stored or looked up in.
+func HashKey(rawKey string) string {
+	return utils.HashBodySHA256([]byte(rawKey))
+}
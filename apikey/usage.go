@@ -0,0 +1,137 @@
+package apikey
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Faze-Technologies/go-utils/logs"
+	"github.com/Faze-Technologies/go-utils/request"
+	"go.uber.org/zap"
+)
+
+// Usage is one key's request count for a billing period.
+type Usage struct {
+	KeyId     string `json:"keyId"`
+	PartnerId string `json:"partnerId"`
+	Period    string `json:"period"`
+	Count     int64  `json:"count"`
+}
+
+func dayPeriod(t time.Time) string   { return t.UTC().Format("20060102") }
+func monthPeriod(t time.Time) string { return t.UTC().Format("200601") }
+
+func usageKey(keyId, period string) string {
+	return fmt.Sprintf("apikey:usage:%s:%s", keyId, period)
+}
+
+// monthKeysSet tracks which keys were used in a month so the export does
+// not have to scan Redis.
+func monthKeysSet(period string) string {
+	return fmt.Sprintf("apikey:usage:keys:%s", period)
+}
+
+// Consume counts one request against key and reports whether it is within
+// quota. Rejected requests are taken back out of the counters, so usage
+// exports only bill requests that were served and a partner over quota does
+// not stay locked out by its own retries.
+func (s *Service) Consume(ctx context.Context, key *Key) error {
+	now := time.Now()
+	month := monthPeriod(now)
+	monthKey, dayKey := usageKey(key.Id, month), usageKey(key.Id, dayPeriod(now))
+
+	monthly, err := s.cache.Incr(ctx, monthKey, monthlyUsageRetention)
+	if err != nil {
+		return fmt.Errorf("apikey: count monthly usage: %w", err)
+	}
+	daily, err := s.cache.Incr(ctx, dayKey, dailyUsageRetention)
+	if err != nil {
+		s.uncount(ctx, monthKey, monthlyUsageRetention)
+		return fmt.Errorf("apikey: count daily usage: %w", err)
+	}
+
+	var quotaErr error
+	switch {
+	case key.DailyQuota > 0 && daily > key.DailyQuota:
+		quotaErr = ErrDailyQuotaExceeded
+	case key.MonthlyQuota > 0 && monthly > key.MonthlyQuota:
+		quotaErr = ErrMonthlyQuotaExceeded
+	}
+	if quotaErr != nil {
+		s.uncount(ctx, monthKey, monthlyUsageRetention)
+		s.uncount(ctx, dayKey, dailyUsageRetention)
+		return quotaErr
+	}
+
+	// Adding to the set is idempotent, so it is repeated on every request
+	// rather than only the first, which would lose the key from the export
+	// for the whole month if that one write failed. The request is already
+	// counted, so a failure is logged and left to the next request.
+	if _, err := s.cache.SAdd(ctx, monthKeysSet(month), key.Id+"|"+key.PartnerId); err != nil {
+		logs.WithContext(ctx).Error("Error tracking API key usage", zap.String("keyId", key.Id), zap.Error(err))
+		return nil
+	}
+	_, _ = s.cache.SetTTL(ctx, monthKeysSet(month), monthlyUsageRetention)
+	return nil
+}
+
+// uncount reverts one Incr of counter. A failure only over-counts, so it is
+// logged rather than returned.
+func (s *Service) uncount(ctx context.Context, counter string, retention time.Duration) {
+	if _, err := s.cache.Decr(ctx, counter, retention); err != nil {
+		logs.WithContext(ctx).Error("Error reverting API key usage", zap.String("counter", counter), zap.Error(err))
+	}
+}
+
+// ExportMonthlyUsage returns every key's request count for the month
+// containing month, sorted by partner then key. Counters are kept for about
+// three months after they are written.
+func (s *Service) ExportMonthlyUsage(ctx context.Context, month time.Time) ([]Usage, error) {
+	period := monthPeriod(month)
+	members, err := s.cache.SMembers(ctx, monthKeysSet(period))
+	if err != nil {
+		return nil, fmt.Errorf("apikey: list used keys: %w", err)
+	}
+
+	type ids struct{ keyId, partnerId string }
+	parsed := make([]ids, 0, len(members))
+	counterKeys := make([]string, 0, len(members))
+	for _, m := range members {
+		keyId, partnerId, _ := strings.Cut(m, "|")
+		parsed = append(parsed, ids{keyId, partnerId})
+		counterKeys = append(counterKeys, usageKey(keyId, period))
+	}
+
+	counts, err := s.cache.GetMultiKeys(ctx, counterKeys)
+	if err != nil {
+		return nil, fmt.Errorf("apikey: read usage counters: %w", err)
+	}
+
+	out := make([]Usage, 0, len(parsed))
+	for i, p := range parsed {
+		n, _ := strconv.ParseInt(counts[counterKeys[i]], 10, 64)
+		out = append(out, Usage{KeyId: p.keyId, PartnerId: p.partnerId, Period: period, Count: n})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].PartnerId != out[j].PartnerId {
+			return out[i].PartnerId < out[j].PartnerId
+		}
+		return out[i].KeyId < out[j].KeyId
+	})
+	return out, nil
+}
+
+// DailyUsage returns the request count for keyId on the day containing day.
+func (s *Service) DailyUsage(ctx context.Context, keyId string, day time.Time) (int64, error) {
+	v, err := s.cache.Get(ctx, usageKey(keyId, dayPeriod(day)))
+	if err != nil {
+		if err.Error() == string(request.KeyNotFoundError) {
+			return 0, nil
+		}
+		return 0, err
+	}
+	return strconv.ParseInt(v, 10, 64)
+}
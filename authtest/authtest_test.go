@@ -0,0 +1,78 @@
+package authtest
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Faze-Technologies/go-utils/middlewares"
+	"github.com/gin-gonic/gin"
+)
+
+func TestKitAuthenticatesMintedTokens(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	kit := New(t)
+	kit.KYC.Set("u2", true, "IN")
+	// u4's KYC is revoked after their token was issued.
+	revokedToken := kit.Token(middlewares.UserDetails{Id: "u4", KycStatus: true, KycCountry: "IN"})
+	kit.KYC.Set("u4", false, "")
+
+	var got *middlewares.UserDetails
+	r := gin.New()
+	r.GET("/me", kit.Middlewares().AuthenticateUser, func(c *gin.Context) {
+		got, _ = middlewares.GetAuthUser(c)
+		c.Status(http.StatusOK)
+	})
+
+	for _, tt := range []struct {
+		name        string
+		token       string
+		wantStatus  int
+		wantKYC     bool
+		wantCountry string
+	}{
+		{"token kyc", kit.Token(middlewares.UserDetails{Id: "u1", KycStatus: true, KycCountry: "US"}), http.StatusOK, true, "US"},
+		{"stubbed kyc", kit.Token(middlewares.UserDetails{Id: "u2"}), http.StatusOK, true, "IN"},
+		{"revoked kyc", revokedToken, http.StatusOK, false, ""},
+		{"unknown kyc", kit.Token(middlewares.UserDetails{Id: "u3"}), http.StatusOK, false, ""},
+		{"expired", kit.Token(middlewares.UserDetails{Id: "u1"}, WithTTL(-time.Hour)), http.StatusUnauthorized, false, ""},
+		{"missing", "", http.StatusUnauthorized, false, ""},
+	} {
+		got = nil
+		req := httptest.NewRequest(http.MethodGet, "/me", nil)
+		if tt.token != "" {
+			req.Header.Set("Authorization", tt.token)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		if w.Code != tt.wantStatus {
+			t.Errorf("%s: status = %d, want %d", tt.name, w.Code, tt.wantStatus)
+			continue
+		}
+		if tt.wantStatus != http.StatusOK {
+			continue
+		}
+		if got == nil {
+			t.Errorf("%s: handler saw no user", tt.name)
+			continue
+		}
+		if got.KycStatus != tt.wantKYC || got.KycCountry != tt.wantCountry {
+			t.Errorf("%s: kyc = (%v, %q), want (%v, %q)", tt.name, got.KycStatus, got.KycCountry, tt.wantKYC, tt.wantCountry)
+		}
+	}
+}
+
+func TestWithUser(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/", nil)
+	c.Request = c.Request.WithContext(WithUser(c.Request.Context(), middlewares.UserDetails{Id: "u9"}))
+
+	user, sErr := middlewares.GetAuthUser(c)
+	if sErr != nil || user.Id != "u9" {
+		t.Fatalf("GetAuthUser() = %+v, %v", user, sErr)
+	}
+}
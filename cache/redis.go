@@ -49,6 +49,11 @@ func NewCache() *Cache {
 	return &Cache{rDB: client}
 }
 
+// Ping checks the Redis connection.
+func (cache Cache) Ping(ctx context.Context) error {
+	return cache.rDB.Ping(ctx).Err()
+}
+
 func (cache Cache) SetJson(ctx context.Context, key string, value interface{}, expiration time.Duration) error {
 	bytes, err := json.Marshal(value)
 	if err != nil {
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/goccy/go-json"
 
@@ -42,6 +43,21 @@ func GetSlice(key string) []string {
 	return viper.GetStringSlice(key)
 }
 
+// GetDurationMs reads an integer number of milliseconds, as stored under the
+// *_ms keys, or returns fallback when key is not set.
+func GetDurationMs(key string, fallback time.Duration) time.Duration {
+	if viper.Get(key) == nil {
+		return fallback
+	}
+	return time.Duration(viper.GetInt(key)) * time.Millisecond
+}
+
+// Set overrides a config value at runtime. Intended for tests and tooling;
+// services should configure through Init.
+func Set(key string, value interface{}) {
+	viper.Set(key, value)
+}
+
 func Init() {
 	envFile := os.Getenv("ENV_FILE")
 	if envFile == "" {
@@ -0,0 +1,90 @@
+// Package deadline propagates a request's remaining time budget to the
+// services it calls. HTTP calls carry the budget as a relative number of
+// milliseconds so clock skew between hosts does not matter; Pub/Sub
+// messages, which may sit in a subscription for a while, carry the absolute
+// deadline instead.
+package deadline
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+)
+
+const (
+	// Header carries the caller's remaining budget in milliseconds.
+	Header = "X-Request-Timeout-Ms"
+	// Attribute carries the absolute deadline, in unix milliseconds, on
+	// Pub/Sub messages.
+	Attribute = "deadlineUnixMs"
+)
+
+// Remaining returns the time left before ctx's deadline, if it has one.
+func Remaining(ctx context.Context) (time.Duration, bool) {
+	d, ok := ctx.Deadline()
+	if !ok {
+		return 0, false
+	}
+	return time.Until(d), true
+}
+
+// FromHeader parses a Header value. Missing, malformed and non-positive
+// values report false.
+func FromHeader(value string) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	ms, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || ms <= 0 {
+		return 0, false
+	}
+	return time.Duration(ms) * time.Millisecond, true
+}
+
+// HeaderValue formats the remaining budget of ctx for Header. An exhausted
+// budget is sent as 1ms rather than omitted so the callee still fails fast.
+func HeaderValue(ctx context.Context) (string, bool) {
+	remaining, ok := Remaining(ctx)
+	if !ok {
+		return "", false
+	}
+	ms := remaining.Milliseconds()
+	if ms < 1 {
+		ms = 1
+	}
+	return strconv.FormatInt(ms, 10), true
+}
+
+// AttachToResty registers an OnBeforeRequest hook that sends the remaining
+// budget of the request context on every outbound call. Like
+// geoip.AttachToResty it relies on callers using .R().SetContext(ctx).
+func AttachToResty(client *resty.Client) {
+	if client == nil {
+		return
+	}
+	client.OnBeforeRequest(func(_ *resty.Client, r *resty.Request) error {
+		if v, ok := HeaderValue(r.Context()); ok {
+			r.SetHeader(Header, v)
+		}
+		return nil
+	})
+}
+
+// InjectAttributes records ctx's deadline in Pub/Sub message attributes.
+func InjectAttributes(ctx context.Context, attrs map[string]string) {
+	if d, ok := ctx.Deadline(); ok {
+		attrs[Attribute] = strconv.FormatInt(d.UnixMilli(), 10)
+	}
+}
+
+// FromAttributes returns the deadline recorded by InjectAttributes. A
+// subscriber can use it to drop work nobody is waiting for any more.
+func FromAttributes(attrs map[string]string) (time.Time, bool) {
+	ms, err := strconv.ParseInt(attrs[Attribute], 10, 64)
+	if err != nil || ms <= 0 {
+		return time.Time{}, false
+	}
+	return time.UnixMilli(ms), true
+}
@@ -0,0 +1,63 @@
+package featureflag
+
+import (
+	"time"
+
+	"github.com/Faze-Technologies/go-utils/config"
+)
+
+const (
+	defaultRedisKey        = "feature-flags"
+	defaultRefreshInterval = 10 * time.Second
+	defaultQASegment       = "qa"
+
+	// OverrideHeader lets QA users force variants for their own requests:
+	// "X-Feature-Flags: new_checkout=on, home_layout=grid".
+	OverrideHeader = "X-Feature-Flags"
+)
+
+// Config controls where flags are loaded from and who may override them.
+//
+//	"feature_flags": {
+//	    "flags": {"new_checkout": {"enabled": true, "rules": [{"segments": ["beta"], "variant": "on"}]}},
+//	    "redis_key": "feature-flags",
+//	    "refresh_interval_ms": 10000,
+//	    "qa_user_ids": ["64f0c2..."],
+//	    "qa_segments": ["qa"]
+//	}
+//
+// Flags under feature_flags.flags are the baseline; a JSON object of the
+// same shape stored in Redis at RedisKey is layered on top, flag by flag, and
+// re-read every RefreshInterval, so flags can be flipped without a deploy.
+// Viper lowercases map keys, so keep flag keys and variant names lowercase.
+type Config struct {
+	RedisKey        string
+	RefreshInterval time.Duration
+	// QAUserIDs and QASegments decide who may use OverrideHeader.
+	QAUserIDs  []string
+	QASegments []string
+}
+
+func defaultConfig() Config {
+	return Config{
+		RedisKey:        defaultRedisKey,
+		RefreshInterval: defaultRefreshInterval,
+		QASegments:      []string{defaultQASegment},
+	}
+}
+
+func mergeFromConfig(cfg Config) Config {
+	if v := config.GetString("feature_flags.redis_key"); v != "" {
+		cfg.RedisKey = v
+	}
+	if ms := config.GetInt("feature_flags.refresh_interval_ms"); ms > 0 {
+		cfg.RefreshInterval = time.Duration(ms) * time.Millisecond
+	}
+	if ids := config.GetSlice("feature_flags.qa_user_ids"); len(ids) > 0 {
+		cfg.QAUserIDs = ids
+	}
+	if segments := config.GetSlice("feature_flags.qa_segments"); len(segments) > 0 {
+		cfg.QASegments = segments
+	}
+	return cfg
+}
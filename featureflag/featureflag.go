@@ -0,0 +1,199 @@
+// Package featureflag evaluates boolean and multivariate feature flags with
+// percentage rollouts and targeting on segments, KYC country, geo country,
+// platform and app version, so features can be toggled without a redeploy:
+//
+//	flags := featureflag.Init(ctx, logger, cache, featureflag.Config{})
+//	defer flags.Stop()
+//	authed := r.Group("/v1", m.AuthenticateUser, geo.Middleware(), flags.Middleware())
+//
+//	// in a handler
+//	if featureflag.Enabled(c.Request.Context(), "new_checkout") { ... }
+//	switch featureflag.Variant(c.Request.Context(), "home_layout") { ... }
+//
+// Definitions (see Flag) come from feature_flags.flags in config, overlaid by
+// a JSON document in Redis that is polled for changes (see Config).
+package featureflag
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/Faze-Technologies/go-utils/config"
+	"github.com/Faze-Technologies/go-utils/request"
+	"go.uber.org/zap"
+)
+
+// Store is where the Redis overlay is read from; *cache.Cache satisfies it.
+type Store interface {
+	Get(ctx context.Context, key string) (string, error)
+}
+
+// Service holds the current flag definitions. It is safe for concurrent use.
+type Service struct {
+	config Config
+	logger *zap.Logger
+	store  Store
+
+	flags  atomic.Pointer[map[string]*compiledFlag]
+	stopCh chan struct{}
+	done   chan struct{}
+}
+
+// Init loads the flags and, if store is non-nil, starts polling it. Any
+// field of opts left at its zero value falls back to the feature_flags.*
+// config keys and then to the package defaults.
+//
+// Init is non-fatal: invalid definitions are logged and skipped, and if
+// Redis is unreachable the config flags are served until it recovers.
+func Init(ctx context.Context, logger *zap.Logger, store Store, opts Config) *Service {
+	cfg := mergeFromConfig(defaultConfig())
+	if opts.RedisKey != "" {
+		cfg.RedisKey = opts.RedisKey
+	}
+	if opts.RefreshInterval > 0 {
+		cfg.RefreshInterval = opts.RefreshInterval
+	}
+	if len(opts.QAUserIDs) > 0 {
+		cfg.QAUserIDs = opts.QAUserIDs
+	}
+	if len(opts.QASegments) > 0 {
+		cfg.QASegments = opts.QASegments
+	}
+
+	s := &Service{config: cfg, logger: logger, store: store, stopCh: make(chan struct{}), done: make(chan struct{})}
+	s.refresh(ctx)
+	if store == nil {
+		close(s.done)
+		return s
+	}
+	go s.poll(context.WithoutCancel(ctx))
+	return s
+}
+
+// Stop halts polling.
+func (s *Service) Stop() {
+	select {
+	case <-s.stopCh:
+	default:
+		close(s.stopCh)
+	}
+	<-s.done
+}
+
+func (s *Service) poll(ctx context.Context) {
+	defer close(s.done)
+	ticker := time.NewTicker(s.config.RefreshInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.stopCh:
+			return
+		case <-ticker.C:
+			s.refresh(ctx)
+		}
+	}
+}
+
+// configFlags decodes feature_flags.flags.
+func configFlags() (map[string]Flag, error) {
+	raw := config.Get("feature_flags.flags")
+	if raw == nil {
+		return nil, nil
+	}
+	b, err := json.Marshal(raw)
+	if err != nil {
+		return nil, err
+	}
+	var defs map[string]Flag
+	if err := json.Unmarshal(b, &defs); err != nil {
+		return nil, err
+	}
+	return defs, nil
+}
+
+// storeFlags reads the Redis overlay. A missing key is not an error.
+func (s *Service) storeFlags(ctx context.Context) (map[string]Flag, error) {
+	if s.store == nil {
+		return nil, nil
+	}
+	raw, err := s.store.Get(ctx, s.config.RedisKey)
+	if err != nil {
+		if err.Error() == string(request.KeyNotFoundError) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var defs map[string]Flag
+	if err := json.Unmarshal([]byte(raw), &defs); err != nil {
+		return nil, fmt.Errorf("featureflag: decoding %s: %w", s.config.RedisKey, err)
+	}
+	return defs, nil
+}
+
+// refresh rebuilds the flag set from config and Redis. If Redis fails, the
+// previous Redis flags are kept rather than silently reverting to config.
+func (s *Service) refresh(ctx context.Context) {
+	defs, err := configFlags()
+	if err != nil {
+		s.logger.Error("Error reading feature flags from config", zap.Error(err))
+	}
+	overlay, err := s.storeFlags(ctx)
+	if err != nil {
+		s.logger.Error("Error reading feature flags from Redis, keeping current flags", zap.Error(err))
+		if s.flags.Load() != nil {
+			return
+		}
+	}
+
+	merged := make(map[string]Flag, len(defs)+len(overlay))
+	for key, f := range defs {
+		merged[strings.ToLower(key)] = f
+	}
+	for key, f := range overlay {
+		merged[strings.ToLower(key)] = f
+	}
+	s.setFlags(merged)
+}
+
+func (s *Service) setFlags(defs map[string]Flag) {
+	compiled := make(map[string]*compiledFlag, len(defs))
+	for key, f := range defs {
+		cf, err := compile(key, f)
+		if err != nil {
+			s.logger.Error("Skipping invalid feature flag", zap.String("flag", key), zap.Error(err))
+			continue
+		}
+		compiled[key] = cf
+	}
+	s.flags.Store(&compiled)
+}
+
+// Evaluate evaluates one flag for subject. Unknown flags evaluate to "off".
+func (s *Service) Evaluate(key string, subject Subject) Evaluation {
+	flags := s.flags.Load()
+	if flags == nil {
+		return Evaluation{Variant: VariantOff, Reason: ReasonUnknownFlag}
+	}
+	f, ok := (*flags)[strings.ToLower(key)]
+	if !ok {
+		return Evaluation{Variant: VariantOff, Reason: ReasonUnknownFlag}
+	}
+	return f.evaluate(subject)
+}
+
+// EvaluateAll evaluates every flag for subject.
+func (s *Service) EvaluateAll(subject Subject) map[string]Evaluation {
+	flags := s.flags.Load()
+	if flags == nil {
+		return map[string]Evaluation{}
+	}
+	evaluations := make(map[string]Evaluation, len(*flags))
+	for key, f := range *flags {
+		evaluations[key] = f.evaluate(subject)
+	}
+	return evaluations
+}
@@ -0,0 +1,175 @@
+package featureflag
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/Faze-Technologies/go-utils/logs"
+	"github.com/Faze-Technologies/go-utils/middlewares"
+	"github.com/Faze-Technologies/go-utils/request"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+type fakeStore struct {
+	mu    sync.Mutex
+	value string
+	err   error
+}
+
+func (f *fakeStore) Get(context.Context, string) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.err != nil {
+		return "", f.err
+	}
+	if f.value == "" {
+		return "", errors.New(string(request.KeyNotFoundError))
+	}
+	return f.value, nil
+}
+
+func (f *fakeStore) set(value string, err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.value, f.err = value, err
+}
+
+func percent(p float64) *float64 { return &p }
+
+func TestEvaluate(t *testing.T) {
+	s := &Service{logger: zap.NewNop()}
+	s.setFlags(map[string]Flag{
+		"checkout": {
+			Enabled:   true,
+			Overrides: map[string]string{"PINNED": VariantOn},
+			Rules: []Rule{
+				{Segments: []string{"beta"}, Variant: VariantOn},
+				{GeoCountries: []string{"IN"}, MinAppVersion: "5.2.0", Variant: VariantOn},
+			},
+		},
+		"killed":  {Enabled: false, Overrides: map[string]string{"pinned": VariantOn}, Rules: []Rule{{Variant: VariantOn}}},
+		"invalid": {Enabled: true, Rules: []Rule{{Variant: "purple"}}},
+	})
+	v520, _ := middlewares.ParseAppVersion("5.2.0")
+	v510, _ := middlewares.ParseAppVersion("5.1.0")
+
+	cases := []struct {
+		name    string
+		flag    string
+		subject Subject
+		want    Evaluation
+	}{
+		{"segment", "checkout", Subject{UserID: "u1", Segments: []string{"Beta"}}, Evaluation{VariantOn, "rule:0"}},
+		{"geo and version", "checkout", Subject{GeoCountry: "in", AppVersion: v520, AppVersionValid: true}, Evaluation{VariantOn, "rule:1"}},
+		{"old version", "checkout", Subject{GeoCountry: "IN", AppVersion: v510, AppVersionValid: true}, Evaluation{VariantOff, ReasonDefault}},
+		{"user override", "Checkout", Subject{UserID: "pinned"}, Evaluation{VariantOn, ReasonUserOverride}},
+		{"disabled", "killed", Subject{UserID: "u1"}, Evaluation{VariantOff, ReasonDisabled}},
+		{"disabled beats override", "killed", Subject{UserID: "pinned"}, Evaluation{VariantOff, ReasonDisabled}},
+		{"invalid skipped", "invalid", Subject{}, Evaluation{VariantOff, ReasonUnknownFlag}},
+		{"unknown", "nope", Subject{}, Evaluation{VariantOff, ReasonUnknownFlag}},
+	}
+	for _, tc := range cases {
+		if got := s.Evaluate(tc.flag, tc.subject); got != tc.want {
+			t.Errorf("%s: got %+v, want %+v", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestRolloutAndSplit(t *testing.T) {
+	s := &Service{logger: zap.NewNop()}
+	s.setFlags(map[string]Flag{
+		"rollout": {Enabled: true, Rules: []Rule{{Percentage: percent(20), Variant: VariantOn}}},
+		"layout": {
+			Enabled:  true,
+			Variants: []string{"control", "grid", "list"},
+			Rules:    []Rule{{Split: map[string]int{"grid": 1, "list": 3}}},
+		},
+	})
+
+	const users = 10000
+	on := 0
+	layouts := map[string]int{}
+	for i := 0; i < users; i++ {
+		subject := Subject{UserID: fmt.Sprintf("user-%d", i)}
+		e := s.Evaluate("rollout", subject)
+		if e.Enabled() {
+			on++
+		}
+		if again := s.Evaluate("rollout", subject); again != e {
+			t.Fatalf("rollout not sticky for %q", subject.UserID)
+		}
+		layouts[s.Evaluate("layout", subject).Variant]++
+	}
+	if on < users*17/100 || on > users*23/100 {
+		t.Errorf("20%% rollout enabled %d of %d users", on, users)
+	}
+	if layouts["control"] != 0 || layouts["grid"] < users*22/100 || layouts["grid"] > users*28/100 {
+		t.Errorf("1:3 split gave %v", layouts)
+	}
+	if e := s.Evaluate("rollout", Subject{}); e.Enabled() {
+		t.Error("anonymous subject should fall outside percentage rollouts")
+	}
+}
+
+func TestRedisOverlay(t *testing.T) {
+	store := &fakeStore{}
+	store.set(`{"checkout": {"enabled": true, "rules": [{"variant": "on"}]}}`, nil)
+	s := Init(context.Background(), zap.NewNop(), store, Config{})
+	defer s.Stop()
+
+	if !s.Evaluate("checkout", Subject{}).Enabled() {
+		t.Fatal("flag from Redis not loaded")
+	}
+
+	// A Redis outage keeps the last known flags.
+	store.set("", errors.New("connection refused"))
+	s.refresh(context.Background())
+	if !s.Evaluate("checkout", Subject{}).Enabled() {
+		t.Fatal("flags lost during Redis outage")
+	}
+
+	store.set(`{"checkout": {"enabled": false}}`, nil)
+	s.refresh(context.Background())
+	if e := s.Evaluate("checkout", Subject{}); e.Reason != ReasonDisabled {
+		t.Fatalf("live update not applied: %+v", e)
+	}
+}
+
+func TestMiddlewareQAOverride(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	logs.NewLogger()
+
+	s := &Service{logger: zap.NewNop(), config: Config{QASegments: []string{"qa"}}}
+	s.setFlags(map[string]Flag{"checkout": {Enabled: false}})
+
+	var user middlewares.UserDetails
+	kit := func(c *gin.Context) {
+		c.Request = c.Request.WithContext(middlewares.ContextWithUser(c.Request.Context(), user))
+	}
+	r := gin.New()
+	r.GET("/x", kit, s.Middleware(), func(c *gin.Context) {
+		c.String(http.StatusOK, Variant(c.Request.Context(), "checkout"))
+	})
+	get := func() string {
+		req := httptest.NewRequest(http.MethodGet, "/x", nil)
+		req.Header.Set(OverrideHeader, "checkout=on, other = beta")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Body.String()
+	}
+
+	user = middlewares.UserDetails{Id: "u1"}
+	if got := get(); got != VariantOff {
+		t.Fatalf("non-QA user overrode flag: %q", got)
+	}
+	user = middlewares.UserDetails{Id: "u2", Segments: []string{"qa"}}
+	if got := get(); got != VariantOn {
+		t.Fatalf("QA override not applied: %q", got)
+	}
+}
@@ -0,0 +1,264 @@
+package featureflag
+
+import (
+	"fmt"
+	"hash/fnv"
+	"strings"
+
+	"github.com/Faze-Technologies/go-utils/middlewares"
+)
+
+// Variants of a boolean flag. A flag that declares no Variants is boolean.
+const (
+	VariantOn  = "on"
+	VariantOff = "off"
+)
+
+// Reasons reported in Evaluation.Reason.
+const (
+	ReasonQAOverride   = "qa_override"
+	ReasonUserOverride = "user_override"
+	ReasonDisabled     = "disabled"
+	ReasonRule         = "rule"
+	ReasonDefault      = "default"
+	ReasonUnknownFlag  = "unknown_flag"
+)
+
+// Flag is a flag definition as stored in config or Redis:
+//
+//	{"enabled": true, "variants": ["control", "grid", "list"], "default": "control",
+//	 "overrides": {"<userId>": "grid"},
+//	 "rules": [
+//	     {"segments": ["staff"], "variant": "grid"},
+//	     {"geoCountries": ["IN"], "minAppVersion": "5.2.0", "split": {"grid": 50, "list": 50}},
+//	     {"percentage": 10, "variant": "list"}]}
+//
+// Rules are tried in order and the first one that matches serves the
+// variant. A disabled flag always serves Off, even to users in Overrides.
+type Flag struct {
+	Enabled  bool     `json:"enabled"`
+	Variants []string `json:"variants,omitempty"`
+	// Default is served when no rule matches; Off when the flag is
+	// disabled. Both default to "off" for boolean flags and to the first
+	// variant otherwise.
+	Default string `json:"default,omitempty"`
+	Off     string `json:"off,omitempty"`
+	// Overrides pins individual users to a variant.
+	Overrides map[string]string `json:"overrides,omitempty"`
+	Rules     []Rule            `json:"rules,omitempty"`
+}
+
+// Rule targets a variant at the users matching all of its conditions. Empty
+// conditions match everyone; list conditions match any of their entries.
+type Rule struct {
+	Segments      []string `json:"segments,omitempty"`
+	KYCCountries  []string `json:"kycCountries,omitempty"`
+	GeoCountries  []string `json:"geoCountries,omitempty"`
+	Platforms     []string `json:"platforms,omitempty"`
+	MinAppVersion string   `json:"minAppVersion,omitempty"`
+
+	// Percentage, from 0 to 100, limits the rule to that share of matching
+	// users, bucketed by a hash of flag key and user id so each user keeps
+	// their answer as the percentage grows. Users outside it, and requests
+	// without a user id, fall through to the next rule. Unset means 100.
+	Percentage *float64 `json:"percentage,omitempty"`
+	// Either Variant is served, or users are split between variants by
+	// weight, bucketed the same way.
+	Variant string         `json:"variant,omitempty"`
+	Split   map[string]int `json:"split,omitempty"`
+}
+
+// Subject is what a flag is evaluated against.
+type Subject struct {
+	UserID     string
+	Segments   []string
+	KYCCountry string
+	GeoCountry string
+	Platform   string
+	// AppVersion is only compared when AppVersionValid is set.
+	AppVersion      middlewares.AppVersion
+	AppVersionValid bool
+}
+
+// Evaluation is the outcome of evaluating one flag.
+type Evaluation struct {
+	Variant string `json:"variant"`
+	Reason  string `json:"reason"`
+}
+
+// Enabled reports whether a boolean flag evaluated to on.
+func (e Evaluation) Enabled() bool {
+	return e.Variant == VariantOn
+}
+
+type compiledRule struct {
+	Rule
+	minAppVersion *middlewares.AppVersion
+	splitOrder    []string
+	splitTotal    int
+}
+
+type compiledFlag struct {
+	key string
+	Flag
+	rules []compiledRule
+}
+
+func containsFold(values []string, want string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, want) {
+			return true
+		}
+	}
+	return false
+}
+
+// compile validates a definition and fills in defaults.
+func compile(key string, f Flag) (*compiledFlag, error) {
+	if len(f.Variants) == 0 {
+		f.Variants = []string{VariantOn, VariantOff}
+		if f.Default == "" {
+			f.Default = VariantOff
+		}
+		if f.Off == "" {
+			f.Off = VariantOff
+		}
+	}
+	if f.Default == "" {
+		f.Default = f.Variants[0]
+	}
+	if f.Off == "" {
+		f.Off = f.Variants[0]
+	}
+
+	cf := &compiledFlag{key: key, Flag: f}
+	valid := func(variant string) error {
+		for _, v := range f.Variants {
+			if v == variant {
+				return nil
+			}
+		}
+		return fmt.Errorf("featureflag: flag %q serves unknown variant %q", key, variant)
+	}
+	for _, variant := range []string{f.Default, f.Off} {
+		if err := valid(variant); err != nil {
+			return nil, err
+		}
+	}
+	// Viper lowercases map keys, so user ids are matched case-insensitively.
+	cf.Overrides = make(map[string]string, len(f.Overrides))
+	for userID, variant := range f.Overrides {
+		if err := valid(variant); err != nil {
+			return nil, err
+		}
+		cf.Overrides[strings.ToLower(userID)] = variant
+	}
+
+	for i, r := range f.Rules {
+		cr := compiledRule{Rule: r}
+		if r.MinAppVersion != "" {
+			v, err := middlewares.ParseAppVersion(r.MinAppVersion)
+			if err != nil {
+				return nil, fmt.Errorf("featureflag: flag %q rule %d: %w", key, i, err)
+			}
+			cr.minAppVersion = &v
+		}
+		if r.Percentage != nil && (*r.Percentage < 0 || *r.Percentage > 100) {
+			return nil, fmt.Errorf("featureflag: flag %q rule %d: percentage %v out of range", key, i, *r.Percentage)
+		}
+		switch {
+		case len(r.Split) > 0:
+			for _, variant := range f.Variants {
+				if weight, ok := r.Split[variant]; ok && weight > 0 {
+					cr.splitOrder = append(cr.splitOrder, variant)
+					cr.splitTotal += weight
+				}
+			}
+			for variant := range r.Split {
+				if err := valid(variant); err != nil {
+					return nil, err
+				}
+			}
+			if cr.splitTotal == 0 {
+				return nil, fmt.Errorf("featureflag: flag %q rule %d: split has no weight", key, i)
+			}
+		case r.Variant != "":
+			if err := valid(r.Variant); err != nil {
+				return nil, err
+			}
+		default:
+			return nil, fmt.Errorf("featureflag: flag %q rule %d serves nothing", key, i)
+		}
+		cf.rules = append(cf.rules, cr)
+	}
+	return cf, nil
+}
+
+// bucket maps a user to [0, 10000) for a given flag and purpose, so that
+// rollout and split buckets are independent of each other and across flags.
+func bucket(flagKey, purpose, userID string) int {
+	h := fnv.New32a()
+	h.Write([]byte(flagKey + "\x00" + purpose + "\x00" + userID))
+	return int(h.Sum32() % 10000)
+}
+
+func (r compiledRule) matches(s Subject) bool {
+	if len(r.Segments) > 0 {
+		matched := false
+		for _, segment := range s.Segments {
+			if containsFold(r.Segments, segment) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	if len(r.KYCCountries) > 0 && !containsFold(r.KYCCountries, s.KYCCountry) {
+		return false
+	}
+	if len(r.GeoCountries) > 0 && !containsFold(r.GeoCountries, s.GeoCountry) {
+		return false
+	}
+	if len(r.Platforms) > 0 && !containsFold(r.Platforms, s.Platform) {
+		return false
+	}
+	if r.minAppVersion != nil && (!s.AppVersionValid || s.AppVersion.Compare(*r.minAppVersion) < 0) {
+		return false
+	}
+	return true
+}
+
+func (f *compiledFlag) evaluate(s Subject) Evaluation {
+	// Disabling a flag is the kill switch, so it wins over user overrides.
+	if !f.Enabled {
+		return Evaluation{Variant: f.Off, Reason: ReasonDisabled}
+	}
+	if variant, ok := f.Overrides[strings.ToLower(s.UserID)]; ok && s.UserID != "" {
+		return Evaluation{Variant: variant, Reason: ReasonUserOverride}
+	}
+	for i, r := range f.rules {
+		if !r.matches(s) {
+			continue
+		}
+		if (r.Percentage != nil || len(r.splitOrder) > 0) && s.UserID == "" {
+			continue
+		}
+		if r.Percentage != nil && float64(bucket(f.key, "rollout", s.UserID)) >= *r.Percentage*100 {
+			continue
+		}
+		reason := fmt.Sprintf("%s:%d", ReasonRule, i)
+		if len(r.splitOrder) == 0 {
+			return Evaluation{Variant: r.Variant, Reason: reason}
+		}
+		point := bucket(f.key, "split", s.UserID) * r.splitTotal / 10000
+		for _, variant := range r.splitOrder {
+			point -= r.Split[variant]
+			if point < 0 {
+				return Evaluation{Variant: variant, Reason: reason}
+			}
+		}
+	}
+	return Evaluation{Variant: f.Default, Reason: ReasonDefault}
+}
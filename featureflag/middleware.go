@@ -0,0 +1,130 @@
+package featureflag
+
+import (
+	"context"
+	"strings"
+
+	"github.com/Faze-Technologies/go-utils/geoip"
+	"github.com/Faze-Technologies/go-utils/logs"
+	"github.com/Faze-Technologies/go-utils/middlewares"
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+type ctxKey struct{}
+
+// Evaluations are the flags evaluated for a request, keyed by lowercased
+// flag key.
+type Evaluations map[string]Evaluation
+
+// FromContext returns the evaluations stored by the middleware, or nil if it
+// did not run.
+func FromContext(ctx context.Context) Evaluations {
+	evaluations, _ := ctx.Value(ctxKey{}).(Evaluations)
+	return evaluations
+}
+
+// Enabled reports whether the boolean flag key is on for the request.
+func Enabled(ctx context.Context, key string) bool {
+	return Variant(ctx, key) == VariantOn
+}
+
+// Variant returns the variant of flag key served to the request, or "off"
+// for unknown flags and requests the middleware did not run for.
+func Variant(ctx context.Context, key string) string {
+	if e, ok := FromContext(ctx)[strings.ToLower(key)]; ok {
+		return e.Variant
+	}
+	return VariantOff
+}
+
+// SubjectFromRequest builds the Subject from the authenticated user, the
+// geoip result and the client headers, whichever are available.
+func SubjectFromRequest(c *gin.Context) Subject {
+	var s Subject
+	if user, err := middlewares.GetAuthUser(c); err == nil {
+		s.UserID = user.Id
+		s.Segments = user.Segments
+		s.KYCCountry = user.KycCountry
+	}
+	if geo := geoip.FromContext(c.Request.Context()); geo != nil {
+		s.GeoCountry = geo.Country
+	}
+	client := middlewares.GetClientInfo(c)
+	s.Platform = client.Platform
+	s.AppVersion, s.AppVersionValid = client.AppVersion, client.AppVersionValid
+	return s
+}
+
+// isQA reports whether subject may override flags with OverrideHeader.
+func (s *Service) isQA(subject Subject) bool {
+	if subject.UserID == "" {
+		return false
+	}
+	for _, id := range s.config.QAUserIDs {
+		if strings.EqualFold(id, subject.UserID) {
+			return true
+		}
+	}
+	for _, segment := range subject.Segments {
+		for _, qa := range s.config.QASegments {
+			if strings.EqualFold(segment, qa) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// applyOverrides applies "flag=variant, flag2=variant2" to evaluations.
+// Variants are not checked against the flag, so QA can also exercise the
+// fallback path of an unknown variant.
+func applyOverrides(evaluations Evaluations, header string) []string {
+	var applied []string
+	for _, pair := range strings.Split(header, ",") {
+		key, variant, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		key, variant = strings.ToLower(strings.TrimSpace(key)), strings.TrimSpace(variant)
+		if !ok || key == "" || variant == "" {
+			continue
+		}
+		evaluations[key] = Evaluation{Variant: variant, Reason: ReasonQAOverride}
+		applied = append(applied, key+"="+variant)
+	}
+	return applied
+}
+
+// Middleware evaluates every flag for the request and stores the result in
+// its context, read with Enabled, Variant or FromContext. Register it after
+// AuthenticateUser and the geoip middleware so targeting sees the user and
+// geo country; on routes without them only app and platform rules apply.
+//
+// Users listed in QAUserIDs or carrying one of QASegments can force variants
+// for their own requests with OverrideHeader. The header is ignored, and
+// logged, for everyone else.
+func (s *Service) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+		subject := SubjectFromRequest(c)
+		evaluations := Evaluations(s.EvaluateAll(subject))
+
+		if header := c.GetHeader(OverrideHeader); header != "" {
+			if s.isQA(subject) {
+				applied := applyOverrides(evaluations, header)
+				trace.SpanFromContext(ctx).SetAttributes(attribute.StringSlice("feature_flags.qa_overrides", applied))
+				logs.WithContext(ctx).Info("Feature flags overridden by QA user",
+					zap.String("userId", subject.UserID),
+					zap.Strings("overrides", applied),
+				)
+			} else {
+				logs.WithContext(ctx).Warn("Ignoring feature flag override from non-QA user",
+					zap.String("userId", subject.UserID),
+				)
+			}
+		}
+
+		c.Request = c.Request.WithContext(context.WithValue(ctx, ctxKey{}, evaluations))
+		c.Next()
+	}
+}
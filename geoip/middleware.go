@@ -22,6 +22,12 @@ func FromContext(ctx context.Context) *GeoResult {
 	return g
 }
 
+// NewContext returns a copy of ctx carrying geo exactly as the middleware
+// stores it, so FromContext finds it.
+func NewContext(ctx context.Context, geo *GeoResult) context.Context {
+	return context.WithValue(ctx, ctxKey{}, geo)
+}
+
 // Middleware returns a Gin handler that enriches the request context with
 // geolocation data. Attach to specific route groups in routes.go — wherever
 // attached, it will always run.
@@ -51,8 +57,7 @@ func (s *Service) Middleware() gin.HandlerFunc {
 			geo = s.engine.lookup(ip)
 		}
 
-		ctx := context.WithValue(c.Request.Context(), ctxKey{}, &geo)
-		c.Request = c.Request.WithContext(ctx)
+		c.Request = c.Request.WithContext(NewContext(c.Request.Context(), &geo))
 		c.Next()
 	}
 }
@@ -8,6 +8,7 @@ require (
 	cloud.google.com/go/secretmanager v1.16.0
 	cloud.google.com/go/storage v1.57.0
 	github.com/aerospike/aerospike-client-go/v6 v6.16.0
+	github.com/andybalholm/brotli v1.2.6
 	github.com/aws/aws-sdk-go v1.55.8
 	github.com/exaring/otelpgx v0.10.0
 	github.com/gin-gonic/gin v1.11.0
@@ -26,7 +27,9 @@ require (
 	go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp v0.63.0
 	go.opentelemetry.io/otel v1.41.1-0.20260303203755-5deb0d31ed71
 	go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc v1.38.0
+	go.opentelemetry.io/otel/metric v1.41.1-0.20260303203755-5deb0d31ed71
 	go.opentelemetry.io/otel/sdk v1.41.1-0.20260303203755-5deb0d31ed71
+	go.opentelemetry.io/otel/sdk/metric v1.41.1-0.20260303203755-5deb0d31ed71
 	go.opentelemetry.io/otel/trace v1.41.1-0.20260303203755-5deb0d31ed71
 	go.uber.org/zap v1.27.0
 	golang.org/x/sync v0.19.0
@@ -108,8 +111,6 @@ require (
 	go.opentelemetry.io/contrib/detectors/gcp v1.38.0 // indirect
 	go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc v0.63.0 // indirect
 	go.opentelemetry.io/otel/exporters/otlp/otlptrace v1.38.0 // indirect
-	go.opentelemetry.io/otel/metric v1.41.1-0.20260303203755-5deb0d31ed71 // indirect
-	go.opentelemetry.io/otel/sdk/metric v1.41.1-0.20260303203755-5deb0d31ed71 // indirect
 	go.opentelemetry.io/proto/otlp v1.8.0 // indirect
 	go.uber.org/mock v0.6.0 // indirect
 	go.uber.org/multierr v1.11.0 // indirect
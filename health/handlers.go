@@ -0,0 +1,46 @@
+package health
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Paths registered by RegisterRoutes.
+const (
+	LivenessPath  = "/healthz/live"
+	ReadinessPath = "/healthz/ready"
+)
+
+func writeReport(c *gin.Context, report Report) {
+	status := http.StatusOK
+	if report.Status != StatusOK {
+		status = http.StatusServiceUnavailable
+	}
+	c.Header("Cache-Control", "no-store")
+	c.JSON(status, report)
+}
+
+// LivenessHandler answers 200 with the liveness report, or 503 when a
+// liveness check fails.
+func (r *Registry) LivenessHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		writeReport(c, r.Liveness(c.Request.Context()))
+	}
+}
+
+// ReadinessHandler answers 200 with the readiness report, or 503 when any
+// required check fails or has not run yet.
+func (r *Registry) ReadinessHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		writeReport(c, r.Readiness(c.Request.Context()))
+	}
+}
+
+// RegisterRoutes mounts both handlers at LivenessPath and ReadinessPath.
+// Register them before authentication and logging middleware, or on a
+// separate router, so probes stay cheap and quiet.
+func (r *Registry) RegisterRoutes(routes gin.IRoutes) {
+	routes.GET(LivenessPath, r.LivenessHandler())
+	routes.GET(ReadinessPath, r.ReadinessHandler())
+}
@@ -0,0 +1,238 @@
+// Package health is a registry of named health checks behind liveness and
+// readiness endpoints:
+//
+//	reg := health.NewRegistry(logger)
+//	reg.Register(health.Redis(cache))
+//	reg.Register(health.Mongo(mongoClient))
+//	reg.Register(health.Lifecycle(app))
+//	reg.Start(ctx)
+//	defer reg.Stop()
+//	reg.RegisterRoutes(router)   // GET /healthz/live, GET /healthz/ready
+//
+// Dependency probes run in the background on their own interval and the
+// endpoints serve the cached results, so a burst of probe traffic never
+// reaches the dependencies. Liveness only runs checks marked Liveness, so a
+// database outage takes the pod out of rotation without getting it
+// restarted.
+package health
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	defaultInterval = 10 * time.Second
+	defaultTimeout  = 2 * time.Second
+)
+
+// Status of a check or of a whole report.
+type Status string
+
+const (
+	StatusOK      Status = "ok"
+	StatusFailing Status = "failing"
+	// StatusPending means a background check has not completed its first
+	// probe yet. It counts as failing.
+	StatusPending Status = "pending"
+)
+
+// errPending is reported for checks that have not run yet.
+var errPending = errors.New("health: first probe pending")
+
+// Check is a named probe.
+type Check struct {
+	Name  string
+	Probe func(ctx context.Context) error
+	// Interval between background probes. Zero runs the probe inline on
+	// every request, which is only appropriate for in-memory checks.
+	Interval time.Duration
+	// Timeout bounds each probe. Defaults to 2s.
+	Timeout time.Duration
+	// Liveness includes the check in the liveness report as well as the
+	// readiness one. Reserve it for failures a restart would fix.
+	Liveness bool
+	// Optional checks are reported but never fail the report.
+	Optional bool
+}
+
+// Result is the latest outcome of one check.
+type Result struct {
+	Status    Status    `json:"status"`
+	Error     string    `json:"error,omitempty"`
+	LatencyMs int64     `json:"latencyMs"`
+	CheckedAt time.Time `json:"checkedAt"`
+	Optional  bool      `json:"optional,omitempty"`
+}
+
+// Report is the JSON body of the health endpoints.
+type Report struct {
+	Status Status            `json:"status"`
+	Checks map[string]Result `json:"checks"`
+}
+
+type entry struct {
+	check  Check
+	mu     sync.RWMutex
+	result Result
+}
+
+func (e *entry) get() Result {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.result
+}
+
+func (e *entry) run(ctx context.Context) Result {
+	ctx, cancel := context.WithTimeout(ctx, e.check.Timeout)
+	defer cancel()
+
+	start := time.Now()
+	err := safeProbe(ctx, e.check.Probe)
+	result := Result{
+		Status:    StatusOK,
+		LatencyMs: time.Since(start).Milliseconds(),
+		CheckedAt: start,
+		Optional:  e.check.Optional,
+	}
+	if err != nil {
+		result.Status = StatusFailing
+		result.Error = err.Error()
+	}
+	return result
+}
+
+// safeProbe turns a panicking probe into a failure instead of crashing the
+// probing goroutine.
+func safeProbe(ctx context.Context, probe func(context.Context) error) (err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("health: probe panicked: %v", p)
+		}
+	}()
+	return probe(ctx)
+}
+
+// Registry holds the registered checks. It is safe for concurrent use.
+type Registry struct {
+	logger *zap.Logger
+
+	mu      sync.RWMutex
+	entries []*entry
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+}
+
+func NewRegistry(logger *zap.Logger) *Registry {
+	return &Registry{logger: logger}
+}
+
+// Register adds a check. Checks registered after Start are probed only
+// once Start is called again.
+func (r *Registry) Register(c Check) {
+	if c.Timeout <= 0 {
+		c.Timeout = defaultTimeout
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.entries = append(r.entries, &entry{
+		check:  c,
+		result: Result{Status: StatusPending, Error: errPending.Error(), Optional: c.Optional},
+	})
+}
+
+// Start launches a background probing loop for every check with an
+// interval. The first probe runs immediately.
+func (r *Registry) Start(ctx context.Context) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.cancel != nil {
+		r.cancel()
+	}
+	ctx, r.cancel = context.WithCancel(ctx)
+
+	for _, e := range r.entries {
+		if e.check.Interval <= 0 {
+			continue
+		}
+		r.wg.Add(1)
+		go r.loop(ctx, e)
+	}
+}
+
+func (r *Registry) loop(ctx context.Context, e *entry) {
+	defer r.wg.Done()
+	ticker := time.NewTicker(e.check.Interval)
+	defer ticker.Stop()
+	for {
+		result := e.run(ctx)
+		e.mu.Lock()
+		changed := e.result.Status != result.Status
+		e.result = result
+		e.mu.Unlock()
+		if changed {
+			r.logger.Info("Health check changed state",
+				zap.String("check", e.check.Name),
+				zap.String("status", string(result.Status)),
+				zap.String("error", result.Error),
+			)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Stop ends the background probing loops.
+func (r *Registry) Stop() {
+	r.mu.Lock()
+	if r.cancel != nil {
+		r.cancel()
+		r.cancel = nil
+	}
+	r.mu.Unlock()
+	r.wg.Wait()
+}
+
+// Readiness reports every check.
+func (r *Registry) Readiness(ctx context.Context) Report {
+	return r.report(ctx, false)
+}
+
+// Liveness reports the checks marked Liveness. With none registered it is
+// always ok: the process answering is proof of life.
+func (r *Registry) Liveness(ctx context.Context) Report {
+	return r.report(ctx, true)
+}
+
+func (r *Registry) report(ctx context.Context, livenessOnly bool) Report {
+	r.mu.RLock()
+	entries := append([]*entry(nil), r.entries...)
+	r.mu.RUnlock()
+
+	report := Report{Status: StatusOK, Checks: map[string]Result{}}
+	for _, e := range entries {
+		if livenessOnly && !e.check.Liveness {
+			continue
+		}
+		var result Result
+		if e.check.Interval <= 0 {
+			result = e.run(ctx)
+		} else {
+			result = e.get()
+		}
+		report.Checks[e.check.Name] = result
+		if result.Status != StatusOK && !e.check.Optional {
+			report.Status = StatusFailing
+		}
+	}
+	return report
+}
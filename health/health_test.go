@@ -0,0 +1,107 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+func TestRegistry(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	var dbCalls atomic.Int32
+	var dbDown atomic.Bool
+	reg := NewRegistry(zap.NewNop())
+	reg.Register(Check{
+		Name:     "db",
+		Interval: 10 * time.Millisecond,
+		Probe: func(context.Context) error {
+			dbCalls.Add(1)
+			if dbDown.Load() {
+				return errors.New("connection refused")
+			}
+			return nil
+		},
+	})
+	reg.Register(Check{
+		Name:     "geo",
+		Interval: time.Hour,
+		Optional: true,
+		Probe:    func(context.Context) error { return errors.New("not loaded") },
+	})
+	reg.Register(Check{
+		Name:     "process",
+		Liveness: true,
+		Probe:    func(context.Context) error { panic("boom") },
+	})
+
+	r := gin.New()
+	reg.RegisterRoutes(r)
+	get := func(path string) (int, Report) {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		var report Report
+		if err := json.Unmarshal(w.Body.Bytes(), &report); err != nil {
+			t.Fatalf("decoding %s: %v", path, err)
+		}
+		return w.Code, report
+	}
+
+	if code, report := get(ReadinessPath); code != http.StatusServiceUnavailable || report.Checks["db"].Status != StatusPending {
+		t.Fatalf("before Start: got %d %+v, want 503 with db pending", code, report)
+	}
+
+	reg.Start(context.Background())
+	defer reg.Stop()
+
+	waitFor := func(status Status) Report {
+		t.Helper()
+		deadline := time.Now().Add(time.Second)
+		for time.Now().Before(deadline) {
+			if _, report := get(ReadinessPath); report.Checks["db"].Status == status {
+				return report
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+		t.Fatalf("db never became %s", status)
+		return Report{}
+	}
+
+	// The panicking inline check fails readiness even with db up; the
+	// optional geo check does not.
+	report := waitFor(StatusOK)
+	if report.Status != StatusFailing || report.Checks["process"].Error == "" {
+		t.Fatalf("panicking check should fail the report: %+v", report)
+	}
+	if report.Checks["geo"].Status != StatusFailing || !report.Checks["geo"].Optional {
+		t.Fatalf("geo: %+v", report.Checks["geo"])
+	}
+
+	code, live := get(LivenessPath)
+	if code != http.StatusServiceUnavailable || len(live.Checks) != 1 {
+		t.Fatalf("liveness: got %d %+v, want 503 with only process", code, live)
+	}
+
+	dbDown.Store(true)
+	if report := waitFor(StatusFailing); report.Checks["db"].Error != "connection refused" {
+		t.Fatalf("db error: %+v", report.Checks["db"])
+	}
+
+	// Requests are served from cache, not by probing.
+	reg.Stop()
+	calls := dbCalls.Load()
+	for i := 0; i < 5; i++ {
+		get(ReadinessPath)
+	}
+	if dbCalls.Load() != calls {
+		t.Fatalf("readiness requests probed db directly")
+	}
+}
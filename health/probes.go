@@ -0,0 +1,122 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/Faze-Technologies/go-utils/cache"
+	"github.com/Faze-Technologies/go-utils/geoip"
+	"github.com/Faze-Technologies/go-utils/lifecycle"
+	"github.com/Faze-Technologies/go-utils/pubsub"
+	"github.com/aerospike/aerospike-client-go/v6"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+// The built-in checks below probe every 10s with a 2s timeout. Adjust the
+// returned Check before registering it to change that.
+
+// Redis sends PING.
+func Redis(c *cache.Cache) Check {
+	return Check{Name: "redis", Interval: defaultInterval, Probe: c.Ping}
+}
+
+// Mongo pings the primary.
+func Mongo(client *mongo.Client) Check {
+	return Check{
+		Name:     "mongo",
+		Interval: defaultInterval,
+		Probe: func(ctx context.Context) error {
+			return client.Ping(ctx, nil)
+		},
+	}
+}
+
+// Postgres pings through the pool, which also fails when every connection
+// is checked out and the pool cannot grow before the timeout.
+func Postgres(pool *pgxpool.Pool) Check {
+	return Check{
+		Name:     "postgres",
+		Interval: defaultInterval,
+		Probe: func(ctx context.Context) error {
+			if err := pool.Ping(ctx); err != nil {
+				stat := pool.Stat()
+				return fmt.Errorf("%w (acquired %d of %d connections)", err, stat.AcquiredConns(), stat.MaxConns())
+			}
+			return nil
+		},
+	}
+}
+
+// Aerospike reports whether the client is connected to at least one node.
+func Aerospike(client *aerospike.Client) Check {
+	return Check{
+		Name:     "aerospike",
+		Interval: defaultInterval,
+		Probe: func(context.Context) error {
+			if !client.IsConnected() {
+				return errors.New("not connected to any cluster node")
+			}
+			return nil
+		},
+	}
+}
+
+// PubSub fails once any receive loop started by StartSubscribers has
+// exited. It passes before StartSubscribers is called.
+func PubSub(ps *pubsub.PubSub) Check {
+	return Check{
+		Name:     "pubsub",
+		Interval: defaultInterval,
+		Probe: func(context.Context) error {
+			var stopped []string
+			for sub, err := range ps.ReceiverStatus() {
+				if err != nil {
+					stopped = append(stopped, sub+": "+err.Error())
+				}
+			}
+			if len(stopped) > 0 {
+				sort.Strings(stopped)
+				return fmt.Errorf("receivers stopped: %s", strings.Join(stopped, "; "))
+			}
+			return nil
+		},
+	}
+}
+
+// GeoIP fails until a database is loaded. It is Optional, since lookups
+// degrade to empty results rather than failing requests.
+func GeoIP(s *geoip.Service) Check {
+	return Check{
+		Name:     "geoip",
+		Interval: defaultInterval,
+		Optional: true,
+		Probe: func(context.Context) error {
+			if !s.Ready() {
+				return errors.New("no database loaded")
+			}
+			return nil
+		},
+	}
+}
+
+// Lifecycle fails until every hook has started and again as soon as the
+// drain begins, so load balancers stop routing before anything is closed.
+// It is evaluated on every request rather than cached.
+func Lifecycle(m *lifecycle.Manager) Check {
+	return Check{
+		Name: "lifecycle",
+		Probe: func(context.Context) error {
+			switch {
+			case m.Draining():
+				return errors.New("draining")
+			case !m.Ready():
+				return errors.New("starting")
+			}
+			return nil
+		},
+	}
+}
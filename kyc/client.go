@@ -0,0 +1,288 @@
+// Package kyc wraps the KYC service's status lookup behind a shared, cached
+// client. One Client should be created at boot and reused: it owns an
+// instrumented HTTP transport with retries and a circuit breaker, collapses
+// concurrent lookups for the same user, and caches results in Redis under
+// kyc:status_country:<userId>.
+//
+// Cached entries are invalidated as soon as the KYC service announces a
+// status change on Pub/Sub (see RegisterHandlers), so a revoked user does not
+// stay verified until the TTL expires.
+package kyc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/Faze-Technologies/go-utils/apm"
+	"github.com/Faze-Technologies/go-utils/cache"
+	"github.com/Faze-Technologies/go-utils/config"
+	"github.com/Faze-Technologies/go-utils/deadline"
+	"github.com/Faze-Technologies/go-utils/logs"
+	"github.com/Faze-Technologies/go-utils/requestid"
+	"github.com/go-resty/resty/v2"
+	"github.com/goccy/go-json"
+	"go.uber.org/zap"
+	"golang.org/x/sync/singleflight"
+)
+
+type APIResponse struct {
+	Success   bool                   `json:"success"`
+	ErrorCode int                    `json:"error_code"`
+	Message   string                 `json:"message"`
+	Data      map[string]interface{} `json:"data"`
+}
+
+// Status is a user's KYC verification state. It is also the cached form.
+type Status struct {
+	Verified bool   `json:"kycStatus"`
+	Country  string `json:"kycCountry"`
+}
+
+// StatusError is returned when the KYC service answers with a non-2xx status.
+type StatusError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("HTTP %d: %s", e.StatusCode, e.Body)
+}
+
+// statusCache is where statuses are cached; *cache.Cache satisfies it.
+type statusCache interface {
+	Get(ctx context.Context, key string) (string, error)
+	Set(ctx context.Context, key string, value string, expiration time.Duration) error
+	Delete(ctx context.Context, key string) error
+	Incr(ctx context.Context, key string, expiration time.Duration) (int64, error)
+}
+
+// Client is the package handle returned by NewClient.
+type Client struct {
+	config  Config
+	cache   statusCache
+	http    *resty.Client
+	group   singleflight.Group
+	breaker *breaker
+	logger  *zap.Logger
+}
+
+// CacheKey returns the Redis key a user's KYC status is cached under.
+func CacheKey(userId string) string {
+	return fmt.Sprintf("kyc:status_country:%s", userId)
+}
+
+// generationKey holds a counter bumped by Invalidate. A lookup only caches
+// its result if the counter did not move while it was in flight, so a stale
+// status fetched before an invalidation cannot be written back after it.
+func generationKey(userId string) string {
+	return fmt.Sprintf("kyc:status_generation:%s", userId)
+}
+
+// NewClient builds a Client. Any field of opts left at its zero value falls
+// back to the kyc_client.* config keys and then to the package defaults. A
+// nil c disables caching, so every lookup goes to the KYC service.
+func NewClient(c *cache.Cache, logger *zap.Logger, opts Config) *Client {
+	cfg := mergeFromConfig(defaultConfig())
+	if opts.ServiceName != "" {
+		cfg.ServiceName = opts.ServiceName
+	}
+	if opts.Timeout > 0 {
+		cfg.Timeout = opts.Timeout
+	}
+	if opts.RetryCount > 0 {
+		cfg.RetryCount = opts.RetryCount
+	} else if opts.RetryCount < 0 {
+		cfg.RetryCount = 0
+	}
+	if opts.RetryWait > 0 {
+		cfg.RetryWait = opts.RetryWait
+	}
+	if opts.VerifiedTTL > 0 {
+		cfg.VerifiedTTL = opts.VerifiedTTL
+	}
+	if opts.UnverifiedTTL > 0 {
+		cfg.UnverifiedTTL = opts.UnverifiedTTL
+	}
+	if opts.BreakerThreshold > 0 {
+		cfg.BreakerThreshold = opts.BreakerThreshold
+	}
+	if opts.BreakerCooldown > 0 {
+		cfg.BreakerCooldown = opts.BreakerCooldown
+	}
+	if opts.StatusChangedTopic != "" {
+		cfg.StatusChangedTopic = opts.StatusChangedTopic
+	}
+
+	httpClient := resty.New().
+		SetTransport(apm.NewHTTPTransport()).
+		SetTimeout(cfg.Timeout).
+		SetRetryCount(cfg.RetryCount).
+		SetRetryWaitTime(cfg.RetryWait).
+		SetHeader("Content-Type", "application/json").
+		AddRetryCondition(func(r *resty.Response, err error) bool {
+			return err != nil || r.StatusCode() >= http.StatusInternalServerError
+		})
+	requestid.AttachToResty(httpClient)
+	deadline.AttachToResty(httpClient)
+
+	client := &Client{
+		config:  cfg,
+		http:    httpClient,
+		breaker: newBreaker(cfg.BreakerThreshold, cfg.BreakerCooldown),
+		logger:  logger,
+	}
+	// Assigned only when set: a nil *cache.Cache in the interface would not
+	// compare equal to nil.
+	if c != nil {
+		client.cache = c
+	}
+	return client
+}
+
+// HTTPClient exposes the shared resty client so callers can attach request
+// hooks (e.g. geoip.AttachToResty) once at boot.
+func (c *Client) HTTPClient() *resty.Client {
+	return c.http
+}
+
+// Status returns the user's KYC status, from cache when possible. Concurrent
+// calls for the same user and IP share a single upstream request.
+func (c *Client) Status(ctx context.Context, userId string, ip string) (Status, error) {
+	logger := logs.WithContextLogger(ctx, c.logger)
+	cacheKey := CacheKey(userId)
+
+	if c.cache != nil {
+		if cached, err := c.cache.Get(ctx, cacheKey); err == nil && cached != "" {
+			var status Status
+			if err := json.Unmarshal([]byte(cached), &status); err != nil {
+				logger.Error("Error unmarshalling cached KYC status", zap.Error(err))
+				return Status{}, fmt.Errorf("unmarshal cached KYC status: %w", err)
+			}
+			logger.Debug("KYC status found in cache", zap.String("userId", userId), zap.Bool("kycStatus", status.Verified))
+			return status, nil
+		}
+	}
+
+	// The upstream call runs detached from the first caller's cancellation so
+	// one aborted request does not fail every caller sharing the flight.
+	v, err, _ := c.group.Do(userId+"\x00"+ip, func() (interface{}, error) {
+		return c.fetchAndCache(context.WithoutCancel(ctx), userId, ip)
+	})
+	if err != nil {
+		return Status{}, err
+	}
+	return v.(Status), nil
+}
+
+func (c *Client) fetchAndCache(ctx context.Context, userId string, ip string) (Status, error) {
+	logger := logs.WithContextLogger(ctx, c.logger)
+
+	if err := c.breaker.allow(); err != nil {
+		return Status{}, err
+	}
+	generation := c.generation(ctx, userId)
+	status, err := c.fetch(ctx, userId, ip)
+	c.breaker.record(breakerError(err))
+	if err != nil {
+		return Status{}, err
+	}
+	if c.cache == nil {
+		return status, nil
+	}
+
+	ttl := c.config.UnverifiedTTL
+	if status.Verified {
+		ttl = c.config.VerifiedTTL
+	}
+	if c.generation(ctx, userId) != generation {
+		logger.Info("KYC status invalidated during lookup, not caching", zap.String("userId", userId))
+		return status, nil
+	}
+	cacheStr, err := json.Marshal(status)
+	if err != nil {
+		logger.Error("Error marshalling KYC cache", zap.Error(err))
+		return Status{}, fmt.Errorf("marshal KYC cache: %w", err)
+	}
+	if err := c.cache.Set(ctx, CacheKey(userId), string(cacheStr), ttl); err != nil {
+		// Don't fail the request if caching fails
+		logger.Error("Error caching KYC status", zap.Error(err))
+	}
+
+	logger.Info("KYC status fetched and cached",
+		zap.String("userId", userId),
+		zap.Bool("kycStatus", status.Verified),
+		zap.Duration("cacheDuration", ttl))
+	return status, nil
+}
+
+// generation reads the user's invalidation counter; a missing key reads as
+// "".
+func (c *Client) generation(ctx context.Context, userId string) string {
+	if c.cache == nil {
+		return ""
+	}
+	v, _ := c.cache.Get(ctx, generationKey(userId))
+	return v
+}
+
+// breakerError returns the part of err the circuit breaker should count: a
+// 4xx answer means the KYC service is up and rejected this one request, so
+// only transport errors and 5xx trip the circuit.
+func breakerError(err error) error {
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) && statusErr.StatusCode < http.StatusInternalServerError {
+		return nil
+	}
+	return err
+}
+
+func (c *Client) fetch(ctx context.Context, userId string, ip string) (Status, error) {
+	logger := logs.WithContextLogger(ctx, c.logger)
+	url := fmt.Sprintf("%s/kyc/getKycStatusAndCountry", config.GetServiceURL(c.config.ServiceName))
+
+	var kycResponse APIResponse
+	resp, err := c.http.R().
+		SetContext(ctx).
+		SetQueryParam("id", userId).
+		SetQueryParam("ip", ip).
+		SetResult(&kycResponse).
+		Get(url)
+	if err != nil {
+		logger.Error("Request to KYC service failed", zap.String("url", url), zap.String("userId", userId), zap.Error(err))
+		return Status{}, fmt.Errorf("request failed: %w", err)
+	}
+	if !resp.IsSuccess() {
+		logger.Error("HTTP error from KYC service", zap.Int("statusCode", resp.StatusCode()), zap.String("body", resp.String()))
+		return Status{}, &StatusError{StatusCode: resp.StatusCode(), Body: resp.String()}
+	}
+
+	kycData := kycResponse.Data
+	status, statusOk := kycData["status"].(string)
+	verified, verifiedOk := kycData["verified"].(string)
+	if (statusOk && status == "completed") || (verifiedOk && verified == "completed") {
+		kycedUser, _ := kycData["user"].(map[string]interface{})
+		country, _ := kycedUser["country"].(string)
+		logger.Info("KYC verified user", zap.String("userId", userId))
+		return Status{Verified: true, Country: country}, nil
+	}
+	logger.Info("KYC not verified", zap.String("userId", userId), zap.String("status", status), zap.String("verified", verified))
+	return Status{}, nil
+}
+
+// Invalidate drops the cached status so the next lookup hits the KYC service,
+// and stops lookups already in flight from caching what they fetched.
+func (c *Client) Invalidate(ctx context.Context, userId string) error {
+	if userId == "" {
+		return errors.New("kyc: empty userId")
+	}
+	if c.cache == nil {
+		return nil
+	}
+	if _, err := c.cache.Incr(ctx, generationKey(userId), c.config.VerifiedTTL); err != nil {
+		return err
+	}
+	return c.cache.Delete(ctx, CacheKey(userId))
+}
@@ -0,0 +1,205 @@
+package kyc
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Faze-Technologies/go-utils/config"
+	"github.com/Faze-Technologies/go-utils/logs"
+	"go.uber.org/zap"
+)
+
+// memoryCache is an in-memory statusCache.
+type memoryCache struct {
+	mu     sync.Mutex
+	values map[string]string
+}
+
+func (m *memoryCache) Get(_ context.Context, key string) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	v, ok := m.values[key]
+	if !ok {
+		return "", errors.New("key not found")
+	}
+	return v, nil
+}
+
+func (m *memoryCache) Set(_ context.Context, key string, value string, _ time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.values[key] = value
+	return nil
+}
+
+func (m *memoryCache) Delete(_ context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.values, key)
+	return nil
+}
+
+func (m *memoryCache) Incr(_ context.Context, key string, _ time.Duration) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	n, _ := strconv.ParseInt(m.values[key], 10, 64)
+	n++
+	m.values[key] = strconv.FormatInt(n, 10)
+	return n, nil
+}
+
+// newUncachedTestClient points a Client without a cache at handler, with a
+// breaker that opens after two failures and no retries.
+func newUncachedTestClient(t *testing.T, handler http.HandlerFunc) *Client {
+	t.Helper()
+	logs.NewLogger()
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+	config.InternalServices["kycTest"] = srv.URL
+	t.Cleanup(func() { delete(config.InternalServices, "kycTest") })
+
+	c := NewClient(nil, zap.NewNop(), Config{
+		ServiceName:      "kycTest",
+		RetryCount:       -1,
+		BreakerThreshold: 2,
+		BreakerCooldown:  time.Minute,
+	})
+	if c.http.RetryCount != 0 {
+		t.Fatalf("RetryCount = %d, want retries disabled", c.http.RetryCount)
+	}
+	return c
+}
+
+// newTestClient is newUncachedTestClient backed by an in-memory cache.
+func newTestClient(t *testing.T, handler http.HandlerFunc) *Client {
+	t.Helper()
+	c := newUncachedTestClient(t, handler)
+	c.cache = &memoryCache{values: map[string]string{}}
+	return c
+}
+
+func TestStatusCachesResult(t *testing.T) {
+	var calls atomic.Int32
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		if r.URL.Query().Get("id") != "u1" || r.URL.Query().Get("ip") != "1.2.3.4" {
+			t.Errorf("query = %s", r.URL.RawQuery)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"success":true,"data":{"status":"completed","user":{"country":"IN"}}}`))
+	})
+
+	for i := 0; i < 2; i++ {
+		status, err := c.Status(context.Background(), "u1", "1.2.3.4")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !status.Verified || status.Country != "IN" {
+			t.Errorf("Status() = %+v, want verified in IN", status)
+		}
+	}
+	if calls.Load() != 1 {
+		t.Errorf("KYC service called %d times, want 1", calls.Load())
+	}
+
+	if err := c.Invalidate(context.Background(), "u1"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Status(context.Background(), "u1", "1.2.3.4"); err != nil || calls.Load() != 2 {
+		t.Errorf("after Invalidate: err = %v, calls = %d, want a fresh lookup", err, calls.Load())
+	}
+}
+
+func TestStatusWithoutCache(t *testing.T) {
+	var calls atomic.Int32
+	c := newUncachedTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"success":true,"data":{"status":"completed","user":{"country":"IN"}}}`))
+	})
+	for i := 0; i < 2; i++ {
+		if status, err := c.Status(context.Background(), "u1", ""); err != nil || !status.Verified {
+			t.Fatalf("Status() = %+v, %v", status, err)
+		}
+	}
+	if calls.Load() != 2 {
+		t.Errorf("KYC service called %d times, want every lookup uncached", calls.Load())
+	}
+	if err := c.Invalidate(context.Background(), "u1"); err != nil {
+		t.Errorf("Invalidate() = %v without a cache", err)
+	}
+}
+
+func TestInvalidateDuringLookup(t *testing.T) {
+	var calls atomic.Int32
+	var c *Client
+	c = newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		// The revocation lands while the first lookup is still in flight.
+		if calls.Add(1) == 1 {
+			if err := c.Invalidate(context.Background(), "u1"); err != nil {
+				t.Error(err)
+			}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"success":true,"data":{"status":"completed","user":{"country":"IN"}}}`))
+	})
+
+	for i := 0; i < 2; i++ {
+		if _, err := c.Status(context.Background(), "u1", ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if calls.Load() != 2 {
+		t.Errorf("KYC service called %d times, want 2: the stale status was cached", calls.Load())
+	}
+}
+
+func TestBreakerIgnoresClientErrors(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unknown user", http.StatusNotFound)
+	})
+	for i := 0; i < 5; i++ {
+		_, err := c.Status(context.Background(), "missing", "")
+		var statusErr *StatusError
+		if !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusNotFound {
+			t.Fatalf("call %d: Status() = %v, want a 404 StatusError", i, err)
+		}
+	}
+}
+
+func TestBreakerOpensOnServerErrors(t *testing.T) {
+	var calls atomic.Int32
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		http.Error(w, "down", http.StatusBadGateway)
+	})
+	for i := 0; i < 2; i++ {
+		if _, err := c.Status(context.Background(), "u1", ""); err == nil {
+			t.Fatalf("call %d: Status() succeeded against a failing service", i)
+		}
+	}
+	if _, err := c.Status(context.Background(), "u1", ""); !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("Status() = %v, want %v", err, ErrCircuitOpen)
+	}
+	if calls.Load() != 2 {
+		t.Errorf("KYC service called %d times, want 2", calls.Load())
+	}
+}
+
+func TestMergeFromConfigIgnoresNonPositiveDurations(t *testing.T) {
+	for _, key := range []string{"kyc_client.timeout_ms", "kyc_client.breaker_cooldown_ms"} {
+		config.Set(key, 0)
+		t.Cleanup(func() { config.Set(key, nil) })
+	}
+	cfg := mergeFromConfig(defaultConfig())
+	if cfg.Timeout != defaultTimeout || cfg.BreakerCooldown != defaultBreakerCooldown {
+		t.Errorf("timeout = %v, cooldown = %v, want the defaults for 0", cfg.Timeout, cfg.BreakerCooldown)
+	}
+}
@@ -0,0 +1,85 @@
+package kyc
+
+import (
+	"time"
+
+	"github.com/Faze-Technologies/go-utils/config"
+)
+
+const (
+	defaultTimeout          = 3 * time.Second
+	defaultRetryCount       = 2
+	defaultRetryWait        = 100 * time.Millisecond
+	defaultVerifiedTTL      = 2 * time.Hour
+	defaultUnverifiedTTL    = 5 * time.Minute
+	defaultBreakerThreshold = 5
+	defaultBreakerCooldown  = 30 * time.Second
+	defaultServiceName      = "kycService"
+	defaultStatusTopic      = "kyc-status-changed"
+)
+
+type Config struct {
+	// ServiceName is looked up through config.GetServiceURL.
+	ServiceName string
+	Timeout     time.Duration
+	// RetryCount is the number of retries after a failed lookup; set it
+	// negative to disable retries.
+	RetryCount    int
+	RetryWait     time.Duration
+	VerifiedTTL   time.Duration
+	UnverifiedTTL time.Duration
+	// BreakerThreshold consecutive failures open the circuit for
+	// BreakerCooldown, during which lookups fail fast.
+	BreakerThreshold int
+	BreakerCooldown  time.Duration
+	// StatusChangedTopic is the Pub/Sub topic the KYC service publishes to
+	// when a user's status changes. See RegisterHandlers.
+	StatusChangedTopic string
+}
+
+func defaultConfig() Config {
+	return Config{
+		ServiceName:        defaultServiceName,
+		Timeout:            defaultTimeout,
+		RetryCount:         defaultRetryCount,
+		RetryWait:          defaultRetryWait,
+		VerifiedTTL:        defaultVerifiedTTL,
+		UnverifiedTTL:      defaultUnverifiedTTL,
+		BreakerThreshold:   defaultBreakerThreshold,
+		BreakerCooldown:    defaultBreakerCooldown,
+		StatusChangedTopic: defaultStatusTopic,
+	}
+}
+
+// durationMs reads a *_ms key. Unlike config.GetDurationMs it ignores values
+// that are not positive: a zero timeout or breaker cooldown would disable
+// the protection rather than configure it.
+func durationMs(key string, fallback time.Duration) time.Duration {
+	if ms := config.GetInt(key); ms > 0 {
+		return time.Duration(ms) * time.Millisecond
+	}
+	return fallback
+}
+
+// mergeFromConfig applies the kyc_client.* config keys on top of cfg.
+func mergeFromConfig(cfg Config) Config {
+	cfg.Timeout = durationMs("kyc_client.timeout_ms", cfg.Timeout)
+	cfg.RetryWait = durationMs("kyc_client.retry_wait_ms", cfg.RetryWait)
+	cfg.BreakerCooldown = durationMs("kyc_client.breaker_cooldown_ms", cfg.BreakerCooldown)
+	if config.Get("kyc_client.retry_count") != nil {
+		cfg.RetryCount = config.GetInt("kyc_client.retry_count")
+	}
+	if s := config.GetInt("kyc_client.verified_ttl_seconds"); s > 0 {
+		cfg.VerifiedTTL = time.Duration(s) * time.Second
+	}
+	if s := config.GetInt("kyc_client.unverified_ttl_seconds"); s > 0 {
+		cfg.UnverifiedTTL = time.Duration(s) * time.Second
+	}
+	if n := config.GetInt("kyc_client.breaker_failure_threshold"); n > 0 {
+		cfg.BreakerThreshold = n
+	}
+	if v := config.GetString("kyc_client.status_changed_topic"); v != "" {
+		cfg.StatusChangedTopic = v
+	}
+	return cfg
+}
@@ -0,0 +1,74 @@
+package kyc
+
+import (
+	"context"
+
+	cloudpubsub "cloud.google.com/go/pubsub"
+	"github.com/goccy/go-json"
+	"go.uber.org/zap"
+
+	"github.com/Faze-Technologies/go-utils/logs"
+	"github.com/Faze-Technologies/go-utils/pubsub"
+)
+
+// StatusChangedEvent is the payload published by the KYC service whenever a
+// user's KYC status changes (verified, revoked, country updated).
+type StatusChangedEvent struct {
+	UserId string `json:"userId"`
+	Status string `json:"status,omitempty"`
+}
+
+// RegisterHandlers adds the status-changed handler to a StartSubscribers
+// handler map under the configured topic. The topic must also be listed in
+// pubSub.subscribers for StartSubscribers to listen on it.
+//
+//	handlers := map[string]pubsub.HandlerFunction{...}
+//	kycClient.RegisterHandlers(handlers)
+//	go ps.StartSubscribers(handlers)
+func (c *Client) RegisterHandlers(handlers map[string]pubsub.HandlerFunction) {
+	handlers[c.config.StatusChangedTopic] = c.HandleStatusChanged
+}
+
+// HandleStatusChanged deletes the cached status for the user named in msg.
+// Malformed messages are acked so they do not redeliver forever; cache
+// failures are nacked so Pub/Sub retries the invalidation.
+func (c *Client) HandleStatusChanged(ctx context.Context, msg *cloudpubsub.Message) {
+	logger := logs.WithContextLogger(ctx, c.logger)
+
+	event, err := decodeStatusChanged(msg.Data)
+	if err != nil || event.UserId == "" {
+		logger.Error("Invalid KYC status changed message", zap.String("messageId", msg.ID), zap.Error(err))
+		msg.Ack()
+		return
+	}
+
+	if err := c.Invalidate(ctx, event.UserId); err != nil {
+		logger.Error("Failed to invalidate KYC status", zap.String("userId", event.UserId), zap.Error(err))
+		msg.Nack()
+		return
+	}
+	logger.Info("KYC status invalidated", zap.String("userId", event.UserId), zap.String("status", event.Status))
+	msg.Ack()
+}
+
+// decodeStatusChanged accepts both the bare event and the {"data": "<json>"}
+// envelope that PubSub.Publish produces for queue-style messages.
+func decodeStatusChanged(data []byte) (StatusChangedEvent, error) {
+	var envelope struct {
+		Data   string `json:"data"`
+		UserId string `json:"userId"`
+		Id     string `json:"id"`
+		Status string `json:"status"`
+	}
+	if err := json.Unmarshal(data, &envelope); err != nil {
+		return StatusChangedEvent{}, err
+	}
+	if envelope.Data != "" {
+		return decodeStatusChanged([]byte(envelope.Data))
+	}
+	event := StatusChangedEvent{UserId: envelope.UserId, Status: envelope.Status}
+	if event.UserId == "" {
+		event.UserId = envelope.Id
+	}
+	return event, nil
+}
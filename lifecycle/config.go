@@ -0,0 +1,45 @@
+package lifecycle
+
+import (
+	"os"
+	"syscall"
+	"time"
+
+	"github.com/Faze-Technologies/go-utils/config"
+)
+
+const (
+	defaultDrainPeriod  = 5 * time.Second
+	defaultStartTimeout = 30 * time.Second
+	defaultStopTimeout  = 15 * time.Second
+)
+
+type Config struct {
+	// DrainPeriod is how long the manager reports not-ready after a
+	// shutdown signal before stopping anything, so load balancers stop
+	// routing new requests first.
+	DrainPeriod time.Duration
+	// StartTimeout and StopTimeout bound hooks that do not set their own.
+	StartTimeout time.Duration
+	StopTimeout  time.Duration
+	// Signals trigger a graceful shutdown in Run. Defaults to SIGTERM and
+	// SIGINT.
+	Signals []os.Signal
+}
+
+func defaultConfig() Config {
+	return Config{
+		DrainPeriod:  defaultDrainPeriod,
+		StartTimeout: defaultStartTimeout,
+		StopTimeout:  defaultStopTimeout,
+		Signals:      []os.Signal{syscall.SIGTERM, os.Interrupt},
+	}
+}
+
+// mergeFromConfig applies the lifecycle.* config keys on top of cfg.
+func mergeFromConfig(cfg Config) Config {
+	cfg.DrainPeriod = config.GetDurationMs("lifecycle.drain_period_ms", cfg.DrainPeriod)
+	cfg.StartTimeout = config.GetDurationMs("lifecycle.start_timeout_ms", cfg.StartTimeout)
+	cfg.StopTimeout = config.GetDurationMs("lifecycle.stop_timeout_ms", cfg.StopTimeout)
+	return cfg
+}
@@ -0,0 +1,40 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+)
+
+// HTTPServer returns a hook that serves srv (typically wrapping a Gin
+// engine) and shuts it down gracefully, letting in-flight requests finish
+// within the hook's stop timeout. The listener is opened in OnStart so a
+// port clash fails startup instead of surfacing later; if serving fails
+// afterwards, the hook calls Fail so Run shuts the service down.
+func HTTPServer(name string, srv *http.Server, dependsOn ...string) Hook {
+	return Hook{
+		Name:      name,
+		DependsOn: dependsOn,
+		OnStart: func(ctx context.Context) error {
+			addr := srv.Addr
+			if addr == "" {
+				addr = ":http"
+			}
+			ln, err := net.Listen("tcp", addr)
+			if err != nil {
+				return err
+			}
+			go func() {
+				if err := srv.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+					// Serve only fails here if the listener breaks; the
+					// process can no longer take traffic.
+					Fail(ctx, fmt.Errorf("HTTP server %q failed: %w", name, err))
+				}
+			}()
+			return nil
+		},
+		OnStop: srv.Shutdown,
+	}
+}
@@ -0,0 +1,293 @@
+// Package lifecycle starts a service's components in dependency order and
+// stops them in reverse, so that on shutdown the HTTP server and Pub/Sub
+// handlers drain before the databases they use are closed, and traces are
+// flushed last:
+//
+//	app := lifecycle.New(logger, lifecycle.Config{})
+//	app.Register(lifecycle.Hook{Name: "tracing", OnStop: tp.Shutdown})
+//	app.Register(lifecycle.Hook{Name: "mongo", DependsOn: []string{"tracing"},
+//		OnStop: func(ctx context.Context) error { return client.Disconnect(ctx) }})
+//	app.Register(lifecycle.Hook{Name: "pubsub", DependsOn: []string{"mongo"},
+//		OnStart: func(context.Context) error { go ps.StartSubscribers(handlers); return nil },
+//		OnStop:  func(context.Context) error { return ps.ClosePubSub() }})
+//	app.Register(lifecycle.HTTPServer("http", srv, "mongo"))
+//	if err := app.Run(context.Background()); err != nil {
+//		logger.Fatal("Service exited with error", zap.Error(err))
+//	}
+//
+// Run blocks until SIGTERM or SIGINT, then fails readiness for the drain
+// period before stopping anything. A hook whose background work dies, such
+// as a server whose listener breaks, reports it with Fail and Run shuts the
+// service down and returns the error instead.
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/signal"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Faze-Technologies/go-utils/logs"
+	"go.uber.org/zap"
+)
+
+// Hook is one component of the service. OnStart must not block: long-running
+// work such as serving or receiving belongs in a goroutine it starts. Either
+// function may be nil.
+type Hook struct {
+	Name string
+	// DependsOn names hooks that must start before this one and stop after
+	// it.
+	DependsOn []string
+	OnStart   func(ctx context.Context) error
+	OnStop    func(ctx context.Context) error
+	// StartTimeout and StopTimeout override the manager's defaults.
+	StartTimeout time.Duration
+	StopTimeout  time.Duration
+}
+
+// Manager runs registered hooks. It is safe for concurrent use; Ready and
+// Draining are meant to be polled by health checks.
+type Manager struct {
+	config Config
+	logger *zap.Logger
+
+	mu      sync.Mutex
+	hooks   []Hook
+	started []Hook
+
+	ready    atomic.Bool
+	draining atomic.Bool
+	failed   chan error
+}
+
+// New builds a Manager. Any field of opts left at its zero value falls back
+// to the lifecycle.* config keys and then to the package defaults; a
+// negative DrainPeriod disables draining.
+func New(logger *zap.Logger, opts Config) *Manager {
+	cfg := mergeFromConfig(defaultConfig())
+	if opts.DrainPeriod != 0 {
+		cfg.DrainPeriod = opts.DrainPeriod
+	}
+	if opts.StartTimeout > 0 {
+		cfg.StartTimeout = opts.StartTimeout
+	}
+	if opts.StopTimeout > 0 {
+		cfg.StopTimeout = opts.StopTimeout
+	}
+	if len(opts.Signals) > 0 {
+		cfg.Signals = opts.Signals
+	}
+	return &Manager{config: cfg, logger: logger, failed: make(chan error, 1)}
+}
+
+type failKey struct{}
+
+// Fail reports that background work started by a hook's OnStart has failed
+// and the service can no longer do its job; ctx is the context OnStart was
+// given. Run then stops every hook and returns err. Only the first failure
+// is kept; later ones are logged.
+func Fail(ctx context.Context, err error) {
+	m, ok := ctx.Value(failKey{}).(*Manager)
+	if !ok {
+		logs.WithContext(ctx).Error("Lifecycle hook failed outside a manager", zap.Error(err))
+		return
+	}
+	select {
+	case m.failed <- err:
+	default:
+		m.logger.Error("Lifecycle hook failed during shutdown", zap.Error(err))
+	}
+}
+
+// Register adds a hook. Hooks must be registered before Start.
+func (m *Manager) Register(h Hook) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.hooks = append(m.hooks, h)
+}
+
+// Ready reports whether every hook has started and no shutdown is under way.
+func (m *Manager) Ready() bool {
+	return m.ready.Load()
+}
+
+// Draining reports whether a shutdown has begun.
+func (m *Manager) Draining() bool {
+	return m.draining.Load()
+}
+
+// order sorts hooks so that every hook comes after its dependencies,
+// keeping registration order otherwise.
+func order(hooks []Hook) ([]Hook, error) {
+	byName := make(map[string]Hook, len(hooks))
+	for _, h := range hooks {
+		if h.Name == "" {
+			return nil, errors.New("lifecycle: hook without a name")
+		}
+		if _, dup := byName[h.Name]; dup {
+			return nil, fmt.Errorf("lifecycle: hook %q registered twice", h.Name)
+		}
+		byName[h.Name] = h
+	}
+
+	const (
+		unvisited = iota
+		visiting
+		done
+	)
+	state := make(map[string]int, len(hooks))
+	sorted := make([]Hook, 0, len(hooks))
+
+	var visit func(h Hook, path []string) error
+	visit = func(h Hook, path []string) error {
+		switch state[h.Name] {
+		case done:
+			return nil
+		case visiting:
+			return fmt.Errorf("lifecycle: dependency cycle %v", append(path, h.Name))
+		}
+		state[h.Name] = visiting
+		for _, dep := range h.DependsOn {
+			d, ok := byName[dep]
+			if !ok {
+				return fmt.Errorf("lifecycle: hook %q depends on unknown hook %q", h.Name, dep)
+			}
+			if err := visit(d, append(path, h.Name)); err != nil {
+				return err
+			}
+		}
+		state[h.Name] = done
+		sorted = append(sorted, h)
+		return nil
+	}
+	for _, h := range hooks {
+		if err := visit(h, nil); err != nil {
+			return nil, err
+		}
+	}
+	return sorted, nil
+}
+
+// runHook calls fn with a deadline of timeout. A hook that overruns is
+// abandoned, not waited for, so one stuck component cannot block shutdown.
+func runHook(ctx context.Context, fn func(context.Context) error, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	errCh := make(chan error, 1)
+	go func() { errCh <- fn(ctx) }()
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Start runs OnStart of every hook in dependency order. If one fails, the
+// hooks already started are stopped again and the error is returned.
+func (m *Manager) Start(ctx context.Context) error {
+	m.mu.Lock()
+	sorted, err := order(m.hooks)
+	m.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	ctx = context.WithValue(ctx, failKey{}, m)
+
+	for _, h := range sorted {
+		if h.OnStart != nil {
+			timeout := h.StartTimeout
+			if timeout <= 0 {
+				timeout = m.config.StartTimeout
+			}
+			begin := time.Now()
+			if err := runHook(ctx, h.OnStart, timeout); err != nil {
+				m.logger.Error("Lifecycle hook failed to start", zap.String("hook", h.Name), zap.Error(err))
+				if stopErr := m.Stop(context.WithoutCancel(ctx)); stopErr != nil {
+					err = errors.Join(err, stopErr)
+				}
+				return fmt.Errorf("lifecycle: starting %q: %w", h.Name, err)
+			}
+			m.logger.Info("Lifecycle hook started", zap.String("hook", h.Name), zap.Duration("took", time.Since(begin)))
+		}
+		m.mu.Lock()
+		m.started = append(m.started, h)
+		m.mu.Unlock()
+	}
+	m.ready.Store(true)
+	return nil
+}
+
+// Stop runs OnStop of every started hook in reverse start order, each under
+// its own timeout. All hooks are stopped even if some fail; the failures are
+// joined into the returned error.
+func (m *Manager) Stop(ctx context.Context) error {
+	m.ready.Store(false)
+	m.draining.Store(true)
+
+	m.mu.Lock()
+	started := m.started
+	m.started = nil
+	m.mu.Unlock()
+
+	var errs []error
+	for i := len(started) - 1; i >= 0; i-- {
+		h := started[i]
+		if h.OnStop == nil {
+			continue
+		}
+		timeout := h.StopTimeout
+		if timeout <= 0 {
+			timeout = m.config.StopTimeout
+		}
+		begin := time.Now()
+		if err := runHook(ctx, h.OnStop, timeout); err != nil {
+			m.logger.Error("Lifecycle hook failed to stop", zap.String("hook", h.Name), zap.Error(err))
+			errs = append(errs, fmt.Errorf("lifecycle: stopping %q: %w", h.Name, err))
+			continue
+		}
+		m.logger.Info("Lifecycle hook stopped", zap.String("hook", h.Name), zap.Duration("took", time.Since(begin)))
+	}
+	return errors.Join(errs...)
+}
+
+// Run starts every hook, waits for a shutdown signal or for ctx to be
+// cancelled, fails readiness for the drain period and then stops every hook.
+func (m *Manager) Run(ctx context.Context) error {
+	if err := m.Start(ctx); err != nil {
+		return err
+	}
+	m.logger.Info("Service started")
+
+	sigCtx, stop := signal.NotifyContext(ctx, m.config.Signals...)
+	var failure error
+	select {
+	case <-sigCtx.Done():
+	case failure = <-m.failed:
+	}
+	stop()
+
+	m.ready.Store(false)
+	m.draining.Store(true)
+	if failure != nil {
+		// Something already stopped taking traffic; draining would only
+		// delay the restart.
+		m.logger.Error("Lifecycle hook failed, shutting down", zap.Error(failure))
+	} else {
+		m.logger.Info("Shutdown requested, draining", zap.Duration("drainPeriod", m.config.DrainPeriod))
+		if m.config.DrainPeriod > 0 {
+			time.Sleep(m.config.DrainPeriod)
+		}
+	}
+	err := m.Stop(context.WithoutCancel(ctx))
+	m.logger.Info("Service stopped")
+	if failure != nil {
+		return errors.Join(fmt.Errorf("lifecycle: %w", failure), err)
+	}
+	return err
+}
@@ -0,0 +1,137 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+type recorder struct {
+	mu     sync.Mutex
+	events []string
+}
+
+func (r *recorder) hook(name string, deps ...string) Hook {
+	record := func(event string) func(context.Context) error {
+		return func(context.Context) error {
+			r.mu.Lock()
+			defer r.mu.Unlock()
+			r.events = append(r.events, event+" "+name)
+			return nil
+		}
+	}
+	return Hook{Name: name, DependsOn: deps, OnStart: record("start"), OnStop: record("stop")}
+}
+
+func TestStartStopOrder(t *testing.T) {
+	r := &recorder{}
+	m := New(zap.NewNop(), Config{})
+	// Registered out of order on purpose.
+	m.Register(r.hook("http", "mongo", "pubsub"))
+	m.Register(r.hook("pubsub", "mongo"))
+	m.Register(r.hook("mongo", "tracing"))
+	m.Register(r.hook("tracing"))
+
+	if err := m.Start(context.Background()); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	if !m.Ready() {
+		t.Error("Ready() = false after Start")
+	}
+	if err := m.Stop(context.Background()); err != nil {
+		t.Fatalf("Stop() error = %v", err)
+	}
+	if m.Ready() || !m.Draining() {
+		t.Error("manager still ready after Stop")
+	}
+
+	want := []string{
+		"start tracing", "start mongo", "start pubsub", "start http",
+		"stop http", "stop pubsub", "stop mongo", "stop tracing",
+	}
+	if !reflect.DeepEqual(r.events, want) {
+		t.Errorf("events = %v, want %v", r.events, want)
+	}
+}
+
+func TestStartFailureStopsStartedHooks(t *testing.T) {
+	r := &recorder{}
+	m := New(zap.NewNop(), Config{})
+	m.Register(r.hook("db"))
+	m.Register(Hook{Name: "broken", DependsOn: []string{"db"}, OnStart: func(context.Context) error {
+		return errors.New("boom")
+	}})
+
+	if err := m.Start(context.Background()); err == nil || !strings.Contains(err.Error(), "boom") {
+		t.Fatalf("Start() error = %v, want boom", err)
+	}
+	if want := []string{"start db", "stop db"}; !reflect.DeepEqual(r.events, want) {
+		t.Errorf("events = %v, want %v", r.events, want)
+	}
+}
+
+func TestStopTimeout(t *testing.T) {
+	r := &recorder{}
+	m := New(zap.NewNop(), Config{StopTimeout: 20 * time.Millisecond})
+	m.Register(r.hook("db"))
+	m.Register(Hook{Name: "stuck", DependsOn: []string{"db"}, OnStop: func(ctx context.Context) error {
+		select {}
+	}})
+	if err := m.Start(context.Background()); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+
+	err := m.Stop(context.Background())
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Stop() error = %v, want deadline exceeded", err)
+	}
+	if want := []string{"start db", "stop db"}; !reflect.DeepEqual(r.events, want) {
+		t.Errorf("events = %v, want %v; a stuck hook must not block the rest", r.events, want)
+	}
+}
+
+func TestInvalidGraph(t *testing.T) {
+	for name, hooks := range map[string][]Hook{
+		"unknown dependency": {{Name: "a", DependsOn: []string{"b"}}},
+		"cycle":              {{Name: "a", DependsOn: []string{"b"}}, {Name: "b", DependsOn: []string{"a"}}},
+		"duplicate":          {{Name: "a"}, {Name: "a"}},
+	} {
+		m := New(zap.NewNop(), Config{})
+		for _, h := range hooks {
+			m.Register(h)
+		}
+		if err := m.Start(context.Background()); err == nil {
+			t.Errorf("%s: Start() error = nil", name)
+		}
+	}
+}
+
+func TestRunStopsOnHookFailure(t *testing.T) {
+	r := &recorder{}
+	m := New(zap.NewNop(), Config{DrainPeriod: time.Hour})
+	m.Register(r.hook("db"))
+	m.Register(Hook{Name: "server", DependsOn: []string{"db"}, OnStart: func(ctx context.Context) error {
+		go Fail(ctx, errors.New("listener closed"))
+		return nil
+	}})
+
+	done := make(chan error, 1)
+	go func() { done <- m.Run(context.Background()) }()
+	select {
+	case err := <-done:
+		if err == nil || !strings.Contains(err.Error(), "listener closed") {
+			t.Fatalf("Run() error = %v, want the hook failure", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run() did not return after a hook failed")
+	}
+	if want := []string{"start db", "stop db"}; !reflect.DeepEqual(r.events, want) {
+		t.Errorf("events = %v, want %v", r.events, want)
+	}
+}
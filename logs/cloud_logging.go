@@ -0,0 +1,136 @@
+package logs
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/Faze-Technologies/go-utils/config"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// FormatCloudLogging selects the GCP Cloud Logging structured format via
+// logging.format. Cloud Logging then reads severity, groups request logs by
+// httpRequest and links entries to traces without a log-based parser.
+const FormatCloudLogging = "gcp"
+
+// Special field names understood by the Cloud Logging agent.
+const (
+	cloudTraceKey        = "logging.googleapis.com/trace"
+	cloudSpanIdKey       = "logging.googleapis.com/spanId"
+	cloudTraceSampledKey = "logging.googleapis.com/trace_sampled"
+)
+
+// cloudLogging is decided once in NewLogger so WithContext does not read
+// config on every call.
+var (
+	cloudLogging bool
+	cloudProject string
+)
+
+// CloudLoggingEnabled reports whether the logger emits the Cloud Logging
+// format.
+func CloudLoggingEnabled() bool {
+	return cloudLogging
+}
+
+func configureCloudLogging(cfg *zap.Config) {
+	cloudLogging = config.GetString("logging.format") == FormatCloudLogging
+	if !cloudLogging {
+		return
+	}
+	cloudProject = config.GetString("logging.gcp_project")
+	if cloudProject == "" {
+		cloudProject = os.Getenv("GOOGLE_CLOUD_PROJECT")
+	}
+
+	cfg.Encoding = "json"
+	cfg.EncoderConfig.LevelKey = "severity"
+	cfg.EncoderConfig.EncodeLevel = encodeCloudSeverity
+	cfg.EncoderConfig.MessageKey = "message"
+	cfg.EncoderConfig.TimeKey = "time"
+	cfg.EncoderConfig.EncodeTime = zapcore.RFC3339NanoTimeEncoder
+	cfg.EncoderConfig.EncodeDuration = zapcore.StringDurationEncoder
+}
+
+// encodeCloudSeverity maps zap levels onto Cloud Logging's LogSeverity names.
+func encodeCloudSeverity(l zapcore.Level, enc zapcore.PrimitiveArrayEncoder) {
+	switch l {
+	case zapcore.DebugLevel:
+		enc.AppendString("DEBUG")
+	case zapcore.InfoLevel:
+		enc.AppendString("INFO")
+	case zapcore.WarnLevel:
+		enc.AppendString("WARNING")
+	case zapcore.ErrorLevel:
+		enc.AppendString("ERROR")
+	case zapcore.DPanicLevel:
+		enc.AppendString("CRITICAL")
+	case zapcore.PanicLevel:
+		enc.AppendString("ALERT")
+	case zapcore.FatalLevel:
+		enc.AppendString("EMERGENCY")
+	default:
+		enc.AppendString("DEFAULT")
+	}
+}
+
+// cloudTraceFields returns the fields Cloud Logging uses to link an entry to
+// its trace. The trace must be fully qualified with the project id.
+func cloudTraceFields(sc trace.SpanContext) []zap.Field {
+	traceName := sc.TraceID().String()
+	if cloudProject != "" {
+		traceName = fmt.Sprintf("projects/%s/traces/%s", cloudProject, traceName)
+	}
+	return []zap.Field{
+		zap.String(cloudTraceKey, traceName),
+		zap.String(cloudSpanIdKey, sc.SpanID().String()),
+		zap.Bool(cloudTraceSampledKey, sc.IsSampled()),
+	}
+}
+
+// HTTPRequest is Cloud Logging's HttpRequest payload. Log it with
+// HTTPRequestField so access logs are grouped and rendered as requests.
+type HTTPRequest struct {
+	RequestMethod string
+	RequestURL    string
+	RequestSize   int64
+	Status        int
+	ResponseSize  int64
+	UserAgent     string
+	RemoteIP      string
+	Referer       string
+	Protocol      string
+	Latency       time.Duration
+}
+
+func (r HTTPRequest) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	enc.AddString("requestMethod", r.RequestMethod)
+	enc.AddString("requestUrl", r.RequestURL)
+	if r.RequestSize > 0 {
+		enc.AddString("requestSize", strconv.FormatInt(r.RequestSize, 10))
+	}
+	enc.AddInt("status", r.Status)
+	if r.ResponseSize >= 0 {
+		enc.AddString("responseSize", strconv.FormatInt(r.ResponseSize, 10))
+	}
+	enc.AddString("userAgent", r.UserAgent)
+	enc.AddString("remoteIp", r.RemoteIP)
+	if r.Referer != "" {
+		enc.AddString("referer", r.Referer)
+	}
+	if r.Protocol != "" {
+		enc.AddString("protocol", r.Protocol)
+	}
+	// Cloud Logging expects a protobuf Duration, e.g. "0.123456789s".
+	enc.AddString("latency", strconv.FormatFloat(r.Latency.Seconds(), 'f', 9, 64)+"s")
+	return nil
+}
+
+// HTTPRequestField wraps r in the "httpRequest" field Cloud Logging reads.
+func HTTPRequestField(r HTTPRequest) zap.Field {
+	return zap.Object("httpRequest", r)
+}
@@ -0,0 +1,149 @@
+package logs
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/Faze-Technologies/go-utils/config"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// captureCloudLogs points the package logger at buf using the Cloud Logging
+// encoder config, restoring the previous state when the test ends.
+func captureCloudLogs(t *testing.T, buf *bytes.Buffer) {
+	prevLogger, prevEnabled, prevProject := logger, cloudLogging, cloudProject
+	t.Cleanup(func() {
+		logger, cloudLogging, cloudProject = prevLogger, prevEnabled, prevProject
+		config.Set("logging.format", "")
+		config.Set("logging.gcp_project", "")
+	})
+	config.Set("logging.format", FormatCloudLogging)
+	config.Set("logging.gcp_project", "my-project")
+
+	cfg := zap.NewProductionConfig()
+	configureCloudLogging(&cfg)
+	logger = zap.New(zapcore.NewCore(zapcore.NewJSONEncoder(cfg.EncoderConfig), zapcore.AddSync(buf), zapcore.DebugLevel))
+}
+
+func decodeEntry(t *testing.T, buf *bytes.Buffer) map[string]interface{} {
+	t.Helper()
+	var entry map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("decoding %q: %v", buf.String(), err)
+	}
+	buf.Reset()
+	return entry
+}
+
+func TestCloudLoggingFields(t *testing.T) {
+	var buf bytes.Buffer
+	captureCloudLogs(t, &buf)
+	if !CloudLoggingEnabled() {
+		t.Fatal("CloudLoggingEnabled() = false with logging.format=gcp")
+	}
+
+	traceID, _ := trace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+	spanID, _ := trace.SpanIDFromHex("00f067aa0ba902b7")
+	ctx := trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID: traceID, SpanID: spanID, TraceFlags: trace.FlagsSampled,
+	}))
+
+	WithContext(ctx).Warn("slow request")
+	entry := decodeEntry(t, &buf)
+	want := map[string]interface{}{
+		"severity":           "WARNING",
+		"message":            "slow request",
+		"trace_id":           "4bf92f3577b34da6a3ce929d0e0e4736",
+		cloudTraceKey:        "projects/my-project/traces/4bf92f3577b34da6a3ce929d0e0e4736",
+		cloudSpanIdKey:       "00f067aa0ba902b7",
+		cloudTraceSampledKey: true,
+	}
+	for key, value := range want {
+		if entry[key] != value {
+			t.Errorf("%s = %v, want %v", key, entry[key], value)
+		}
+	}
+	if _, err := time.Parse(time.RFC3339Nano, entry["time"].(string)); err != nil {
+		t.Errorf("time %q is not RFC 3339: %v", entry["time"], err)
+	}
+
+	// Without a span the Cloud Logging trace fields are left out.
+	WithContext(context.Background()).Error("failed")
+	entry = decodeEntry(t, &buf)
+	if entry["severity"] != "ERROR" {
+		t.Errorf("severity = %v, want ERROR", entry["severity"])
+	}
+	if _, ok := entry[cloudTraceKey]; ok {
+		t.Errorf("%s set without a span", cloudTraceKey)
+	}
+}
+
+func TestCloudSeverity(t *testing.T) {
+	cases := map[zapcore.Level]string{
+		zapcore.DebugLevel:  "DEBUG",
+		zapcore.InfoLevel:   "INFO",
+		zapcore.WarnLevel:   "WARNING",
+		zapcore.ErrorLevel:  "ERROR",
+		zapcore.DPanicLevel: "CRITICAL",
+		zapcore.PanicLevel:  "ALERT",
+		zapcore.FatalLevel:  "EMERGENCY",
+	}
+	for level, want := range cases {
+		enc := zapcore.NewMapObjectEncoder()
+		if err := enc.AddArray("s", zapcore.ArrayMarshalerFunc(func(arr zapcore.ArrayEncoder) error {
+			encodeCloudSeverity(level, arr)
+			return nil
+		})); err != nil {
+			t.Fatal(err)
+		}
+		if got := enc.Fields["s"].([]interface{})[0]; got != want {
+			t.Errorf("%v = %v, want %s", level, got, want)
+		}
+	}
+}
+
+func TestHTTPRequestField(t *testing.T) {
+	var buf bytes.Buffer
+	captureCloudLogs(t, &buf)
+
+	GetLogger().Info("request", HTTPRequestField(HTTPRequest{
+		RequestMethod: "POST",
+		RequestURL:    "/v1/orders?id=1",
+		RequestSize:   512,
+		Status:        201,
+		ResponseSize:  0,
+		UserAgent:     "app/5.2.0",
+		RemoteIP:      "203.0.113.7",
+		Protocol:      "HTTP/1.1",
+		Latency:       1500 * time.Millisecond,
+	}))
+	entry := decodeEntry(t, &buf)
+	got, ok := entry["httpRequest"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("httpRequest = %v", entry["httpRequest"])
+	}
+	want := map[string]interface{}{
+		"requestMethod": "POST",
+		"requestUrl":    "/v1/orders?id=1",
+		"requestSize":   "512",
+		"status":        float64(201),
+		"responseSize":  "0",
+		"userAgent":     "app/5.2.0",
+		"remoteIp":      "203.0.113.7",
+		"protocol":      "HTTP/1.1",
+		"latency":       "1.500000000s",
+	}
+	for key, value := range want {
+		if got[key] != value {
+			t.Errorf("httpRequest.%s = %v, want %v", key, got[key], value)
+		}
+	}
+	if _, ok := got["referer"]; ok {
+		t.Error("empty referer was logged")
+	}
+}
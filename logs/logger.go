@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/Faze-Technologies/go-utils/config"
+	"github.com/Faze-Technologies/go-utils/requestid"
 
 	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
@@ -35,6 +36,7 @@ func NewLogger() *zap.Logger {
 	cfg.EncoderConfig.EncodeTime = func(t time.Time, enc zapcore.PrimitiveArrayEncoder) {
 		enc.AppendString(t.In(ist).Format("2006-01-02T15:04:05.000Z07:00"))
 	}
+	configureCloudLogging(&cfg)
 
 	var err error
 	logger, err = cfg.Build(zap.AddCaller())
@@ -50,15 +52,34 @@ func GetLogger() *zap.Logger {
 
 // WithContext returns a logger with trace_id and span_id fields extracted
 // from the OTel span in ctx. SigNoz uses these fields to correlate logs to traces.
+// The request_id field is added whenever ctx carries one, and in the Cloud
+// Logging format the trace is also linked via logging.googleapis.com/trace.
 func WithContext(ctx context.Context) *zap.Logger {
+	return WithContextLogger(ctx, logger)
+}
+
+// WithContextLogger is WithContext for a logger handed in by the caller,
+// such as one passed to a middleware constructor. A nil l falls back to the
+// package logger.
+func WithContextLogger(ctx context.Context, l *zap.Logger) *zap.Logger {
+	if l == nil {
+		l = logger
+	}
+	if id := requestid.FromContext(ctx); id != "" {
+		l = l.With(zap.String("request_id", id))
+	}
 	span := trace.SpanFromContext(ctx)
 	if !span.SpanContext().IsValid() {
-		return logger
+		return l
 	}
 	sc := span.SpanContext()
-	return logger.With(
+	fields := []zap.Field{
 		zap.String("trace_id", sc.TraceID().String()),
 		zap.String("span_id", sc.SpanID().String()),
 		zap.String("trace_flags", sc.TraceFlags().String()),
-	)
+	}
+	if cloudLogging {
+		fields = append(fields, cloudTraceFields(sc)...)
+	}
+	return l.With(fields...)
 }
@@ -0,0 +1,92 @@
+package middlewares
+
+import (
+	"strings"
+
+	"github.com/Faze-Technologies/go-utils/config"
+	"github.com/Faze-Technologies/go-utils/logs"
+	"github.com/Faze-Technologies/go-utils/request"
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+// AppVersionPolicy sets the oldest app version allowed per platform.
+// Platforms without an entry, such as web, are not checked.
+type AppVersionPolicy struct {
+	MinVersions map[string]AppVersion
+	// StoreURLs, by platform, are returned with the force-upgrade error so
+	// the app can link straight to the update.
+	StoreURLs map[string]string
+	// AllowMissing lets requests through that carry no parseable
+	// appversion header. By default they are treated as outdated, since
+	// only very old builds omit it.
+	AllowMissing bool
+}
+
+// LoadAppVersionPolicy reads an AppVersionPolicy from config under prefix,
+// e.g. "app_version":
+//
+//	"app_version": {"min": {"android": "5.2.0", "ios": "5.1.3"},
+//	    "store_urls": {"android": "https://play.google.com/store/apps/details?id=..."},
+//	    "allow_missing": false}
+//
+// Unparseable versions are logged and skipped.
+func LoadAppVersionPolicy(prefix string) AppVersionPolicy {
+	p := AppVersionPolicy{
+		MinVersions:  map[string]AppVersion{},
+		StoreURLs:    map[string]string{},
+		AllowMissing: config.GetBool(prefix + ".allow_missing"),
+	}
+	for platform, raw := range config.GetStringMap(prefix + ".min") {
+		v, err := ParseAppVersion(raw)
+		if err != nil {
+			logs.GetLogger().Error("Ignoring invalid minimum app version",
+				zap.String("platform", platform), zap.Error(err))
+			continue
+		}
+		p.MinVersions[strings.ToLower(platform)] = v
+	}
+	for platform, url := range config.GetStringMap(prefix + ".store_urls") {
+		p.StoreURLs[strings.ToLower(platform)] = url
+	}
+	return p
+}
+
+// RequireMinAppVersion rejects requests from app builds older than the
+// platform's minimum with 426 and the forceUpgradeError code, which the
+// apps answer with the blocking upgrade screen. The error data carries
+// platform, minVersion and, if configured, storeUrl.
+func RequireMinAppVersion(policy AppVersionPolicy) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		info := GetClientInfo(c)
+		minVersion, ok := policy.MinVersions[info.Platform]
+		if !ok {
+			c.Next()
+			return
+		}
+		if info.AppVersionValid && info.AppVersion.Compare(minVersion) >= 0 {
+			c.Next()
+			return
+		}
+		if !info.AppVersionValid && policy.AllowMissing {
+			c.Next()
+			return
+		}
+
+		ctx := c.Request.Context()
+		trace.SpanFromContext(ctx).SetAttributes(attribute.Bool("app.force_upgrade", true))
+		logs.WithContext(ctx).Info("App version below minimum, forcing upgrade",
+			zap.String("platform", info.Platform),
+			zap.String("appVersion", info.RawAppVersion),
+			zap.String("minVersion", minVersion.String()),
+		)
+		data := gin.H{"platform": info.Platform, "minVersion": minVersion.String()}
+		if url := policy.StoreURLs[info.Platform]; url != "" {
+			data["storeUrl"] = url
+		}
+		request.SendServiceError(c, request.CreateForceUpgradeError(nil, data))
+		c.Abort()
+	}
+}
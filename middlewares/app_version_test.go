@@ -0,0 +1,91 @@
+package middlewares
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Faze-Technologies/go-utils/logs"
+	"github.com/gin-gonic/gin"
+)
+
+func TestAppVersionCompare(t *testing.T) {
+	cases := []struct {
+		a, b string
+		want int
+	}{
+		{"5.2.0", "5.2.0", 0},
+		{"v5.2", "5.2.0", 0},
+		{"5.10.0", "5.9.9", 1},
+		{"5.2.0-beta.1", "5.2.0", -1},
+		{"5.2.0-beta.10", "5.2.0-beta.2", 1},
+		{"5.2.0-beta", "5.2.0-beta.1", -1},
+		{"5.2.0-alpha.9", "5.2.0-beta.1", -1},
+		{"5.2.0-1", "5.2.0-rc", -1},
+		{"5.2.0+1234", "5.2.0", 0},
+		{"4", "4.0.1", -1},
+	}
+	for _, tc := range cases {
+		a, errA := ParseAppVersion(tc.a)
+		b, errB := ParseAppVersion(tc.b)
+		if errA != nil || errB != nil {
+			t.Fatalf("parse %q / %q: %v %v", tc.a, tc.b, errA, errB)
+		}
+		if got := a.Compare(b); got != tc.want {
+			t.Errorf("%s vs %s = %d, want %d", tc.a, tc.b, got, tc.want)
+		}
+	}
+	for _, bad := range []string{"", "abc", "1.2.3.4", "1.x"} {
+		if _, err := ParseAppVersion(bad); err == nil {
+			t.Errorf("ParseAppVersion(%q) accepted", bad)
+		}
+	}
+}
+
+func TestRequireMinAppVersion(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	logs.NewLogger()
+
+	minAndroid, _ := ParseAppVersion("5.2.0")
+	r := gin.New()
+	r.Use(ParseUserAgent(), RequireMinAppVersion(AppVersionPolicy{
+		MinVersions: map[string]AppVersion{PlatformAndroid: minAndroid},
+		StoreURLs:   map[string]string{PlatformAndroid: "https://play.example.com"},
+	}))
+	r.GET("/x", func(c *gin.Context) {
+		info, ok := ClientInfoFromContext(c.Request.Context())
+		if !ok {
+			t.Error("ClientInfo missing from context")
+		}
+		c.String(http.StatusOK, info.Platform)
+	})
+
+	get := func(platform, version string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/x", nil)
+		req.Header.Set(PlatformHeader, platform)
+		req.Header.Set(AppVersionHeader, version)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := get("Android", "5.2.1"); w.Code != http.StatusOK || w.Body.String() != PlatformAndroid {
+		t.Fatalf("current android: got %d %q", w.Code, w.Body.String())
+	}
+	if w := get("web", ""); w.Code != http.StatusOK {
+		t.Fatalf("unconfigured platform: got %d", w.Code)
+	}
+	for _, version := range []string{"5.1.9", "", "garbage"} {
+		w := get("android", version)
+		var body struct {
+			Error string            `json:"error"`
+			Data  map[string]string `json:"data"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &body)
+		if w.Code != http.StatusUpgradeRequired || body.Error != "forceUpgradeError" ||
+			body.Data["minVersion"] != "5.2.0" || body.Data["storeUrl"] != "https://play.example.com" {
+			t.Fatalf("android %q: got %d %s", version, w.Code, w.Body.String())
+		}
+	}
+}
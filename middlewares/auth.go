@@ -2,36 +2,67 @@ package middlewares
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
 	"time"
 
 	"github.com/Faze-Technologies/go-utils/cache"
 	"github.com/Faze-Technologies/go-utils/config"
+	"github.com/Faze-Technologies/go-utils/kyc"
 	"github.com/Faze-Technologies/go-utils/logs"
 	"github.com/Faze-Technologies/go-utils/request"
+	"github.com/Faze-Technologies/go-utils/revocation"
 	"github.com/gin-gonic/gin"
-	"github.com/go-resty/resty/v2"
 	"github.com/goccy/go-json"
 	"github.com/golang-jwt/jwt/v4"
 	"go.uber.org/zap"
 )
 
+// KYCStatusProvider resolves a user's KYC status. *kyc.Client is the
+// production implementation; tests can substitute a stub.
+type KYCStatusProvider interface {
+	Status(ctx context.Context, userId string, ip string) (kyc.Status, error)
+}
+
 type Middlewares struct {
 	Cache  *cache.Cache
 	Logger *zap.Logger
+	KYC    KYCStatusProvider
+	// Revocation is consulted by AuthenticateUser and
+	// AuthenticateUserOptional; nil disables the check. InitializeMiddlewares
+	// only sets it when auth.revocation.enabled is true.
+	Revocation *revocation.Store
+	// Users loads impersonation targets; nil disables X-Act-As-User.
+	Users UserLoader
+	// Audit receives impersonation audit events; nil only logs them.
+	Audit AuditPublisher
+
+	// stepUpGrants replaces Cache for MFA step-up grants in tests.
+	stepUpGrants stepUpStore
 }
 
 func InitializeMiddlewares(cache *cache.Cache, logger *zap.Logger) *Middlewares {
-	return &Middlewares{
+	m := &Middlewares{
 		Cache:  cache,
 		Logger: logger,
+		KYC:    kyc.NewClient(cache, logger, kyc.Config{}),
+	}
+	// The revocation check adds a Redis lookup to authenticated requests (at
+	// most one per local cache window), so services opt in to it.
+	if cache != nil && config.GetBool("auth.revocation.enabled") {
+		m.Revocation = revocation.NewStore(cache, 0)
 	}
+	return m
 }
 
 type contextKey string
 
-const userContextKey contextKey = "user"
+const (
+	userContextKey        contextKey = "user"
+	claimsContextKey      contextKey = "tokenClaims"
+	accessTokenContextKey contextKey = "accessToken"
+)
 
 type UserDetails struct {
 	Id            string                 `json:"id"`
@@ -59,138 +90,310 @@ func GetAuthUser(c *gin.Context) (*UserDetails, *request.ServiceError) {
 	return &user, nil
 }
 
+// ContextWithUser returns a copy of ctx carrying user exactly as
+// AuthenticateUser stores it, so GetAuthUser finds it.
+func ContextWithUser(ctx context.Context, user UserDetails) context.Context {
+	return context.WithValue(ctx, userContextKey, user)
+}
+
+// GetTokenClaims returns the verified claims of the access token that
+// authenticated the request, or nil when the request is unauthenticated.
+func GetTokenClaims(c *gin.Context) jwt.MapClaims {
+	claims, _ := c.Request.Context().Value(claimsContextKey).(jwt.MapClaims)
+	return claims
+}
+
+// Default token validation settings. Issuer and audience are only enforced
+// when configured so services can roll the check out one at a time.
+const defaultTokenLeeway = 30 * time.Second
+
+var defaultAllowedAlgorithms = []string{"RS256", "ES256"}
+
+// Token rejection reasons. They are carried as the wrapped error of the
+// ServiceError returned by verifyTokenSignature so they show up in logs,
+// while the client only ever sees the generic "Invalid Access Token".
+var (
+	ErrTokenMalformed        = errors.New("token is malformed")
+	ErrTokenAlgorithm        = errors.New("token signing algorithm is not allowed")
+	ErrTokenSignatureInvalid = errors.New("token signature is invalid")
+	ErrTokenMissingExpiry    = errors.New("token has no exp claim")
+	ErrTokenExpired          = errors.New("token is expired")
+	ErrTokenNotValidYet      = errors.New("token is not valid yet")
+	ErrTokenUsedBeforeIssued = errors.New("token used before issued")
+	ErrTokenInvalidIssuer    = errors.New("token has invalid issuer")
+	ErrTokenInvalidAudience  = errors.New("token has invalid audience")
+)
+
+type tokenValidationConfig struct {
+	algorithms []string
+	issuer     string
+	audiences  []string
+	leeway     time.Duration
+}
+
+func loadTokenValidationConfig() tokenValidationConfig {
+	cfg := tokenValidationConfig{
+		algorithms: config.GetSlice("auth.allowed_algorithms"),
+		issuer:     config.GetString("auth.issuer"),
+		audiences:  config.GetSlice("auth.audience"),
+		leeway:     defaultTokenLeeway,
+	}
+	if len(cfg.algorithms) == 0 {
+		cfg.algorithms = defaultAllowedAlgorithms
+	}
+	if config.Get("auth.leeway_seconds") != nil {
+		cfg.leeway = time.Duration(config.GetInt("auth.leeway_seconds")) * time.Second
+	}
+	return cfg
+}
+
+// parseVerificationKey accepts either an RSA or an EC public key in PEM form,
+// matching the RS256 and ES256 algorithms we allow.
+func parseVerificationKey(pemKey string) (interface{}, error) {
+	keyBytes := []byte(pemKey)
+	if key, err := jwt.ParseRSAPublicKeyFromPEM(keyBytes); err == nil {
+		return key, nil
+	}
+	key, err := jwt.ParseECPublicKeyFromPEM(keyBytes)
+	if err != nil {
+		return nil, fmt.Errorf("auth_public_key is neither an RSA nor an EC public key: %w", err)
+	}
+	return key, nil
+}
+
+func algorithmAllowed(alg string, allowed []string) bool {
+	for _, a := range allowed {
+		if a == alg {
+			return true
+		}
+	}
+	return false
+}
+
+// classifyParseError maps a jwt parse error onto one of our rejection reasons.
+func classifyParseError(err error) error {
+	if errors.Is(err, ErrTokenAlgorithm) {
+		return ErrTokenAlgorithm
+	}
+	var vErr *jwt.ValidationError
+	if errors.As(err, &vErr) && vErr.Errors&jwt.ValidationErrorSignatureInvalid != 0 {
+		return ErrTokenSignatureInvalid
+	}
+	return ErrTokenMalformed
+}
+
+// validateClaims checks the registered claims with clock-skew leeway. exp is
+// mandatory; iss and aud are only checked when configured.
+func validateClaims(claims jwt.MapClaims, cfg tokenValidationConfig, now time.Time) error {
+	if _, ok := claims["exp"]; !ok {
+		return ErrTokenMissingExpiry
+	}
+	if !claims.VerifyExpiresAt(now.Add(-cfg.leeway).Unix(), true) {
+		return ErrTokenExpired
+	}
+	if !claims.VerifyNotBefore(now.Add(cfg.leeway).Unix(), false) {
+		return ErrTokenNotValidYet
+	}
+	if !claims.VerifyIssuedAt(now.Add(cfg.leeway).Unix(), false) {
+		return ErrTokenUsedBeforeIssued
+	}
+	if cfg.issuer != "" && !claims.VerifyIssuer(cfg.issuer, true) {
+		return ErrTokenInvalidIssuer
+	}
+	if len(cfg.audiences) > 0 {
+		matched := false
+		for _, aud := range cfg.audiences {
+			if claims.VerifyAudience(aud, true) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return ErrTokenInvalidAudience
+		}
+	}
+	return nil
+}
+
 func verifyTokenSignature(ctx context.Context, token string) (*jwt.Token, *request.ServiceError) {
 	logger := logs.WithContext(ctx)
-	publicKey := config.GetString("auth_public_key")
-	publicKeyBytes := []byte(publicKey)
-
-	publicKeyParsed, err := jwt.ParseRSAPublicKeyFromPEM(publicKeyBytes)
+	publicKeyParsed, err := parseVerificationKey(config.GetString("auth_public_key"))
 	if err != nil {
 		logger.Error("Error parsing public key", zap.Error(err))
 		return nil, request.CreateInternalServerError(err)
 	}
 
-	parsedToken, err := jwt.Parse(token, func(token *jwt.Token) (interface{}, error) {
+	cfg := loadTokenValidationConfig()
+	parser := jwt.NewParser(
+		jwt.WithJSONNumber(),
+		// Registered claims are validated below so leeway and the
+		// missing-exp rule apply.
+		jwt.WithoutClaimsValidation(),
+	)
+
+	// The algorithm is pinned in the key func rather than via
+	// jwt.WithValidMethods so a disallowed alg (including "none") surfaces as
+	// its own reason instead of a generic signature failure.
+	parsedToken, err := parser.Parse(token, func(token *jwt.Token) (interface{}, error) {
+		if !algorithmAllowed(token.Method.Alg(), cfg.algorithms) {
+			return nil, ErrTokenAlgorithm
+		}
 		return publicKeyParsed, nil
 	})
 	if err != nil {
-		logger.Error("Error parsing token", zap.Error(err))
-		return nil, request.CreateUnauthorizedError(err, "Invalid Access Token")
+		reason := classifyParseError(err)
+		logger.Warn("Rejected access token", zap.String("reason", reason.Error()), zap.Error(err))
+		return nil, request.CreateUnauthorizedError(reason, "Invalid Access Token")
 	}
 	if !parsedToken.Valid {
+		return nil, request.CreateUnauthorizedError(ErrTokenSignatureInvalid, "Invalid Access Token")
+	}
+
+	claims, ok := parsedToken.Claims.(jwt.MapClaims)
+	if !ok {
+		return nil, request.CreateUnauthorizedError(ErrTokenMalformed, "Invalid Access Token")
+	}
+	if err := validateClaims(claims, cfg, time.Now()); err != nil {
+		logger.Warn("Rejected access token", zap.String("reason", err.Error()))
 		return nil, request.CreateUnauthorizedError(err, "Invalid Access Token")
 	}
 	return parsedToken, nil
 }
 
-type KYCAPIResponse struct {
-	Success   bool                   `json:"success"`
-	ErrorCode int                    `json:"error_code"`
-	Message   string                 `json:"message"`
-	Data      map[string]interface{} `json:"data"`
-}
+// KYCAPIResponse is kept for callers that decoded KYC responses themselves.
+type KYCAPIResponse = kyc.APIResponse
+
+// ErrNoKYCProvider is returned when a token needs a KYC lookup but the
+// Middlewares were built without a KYC provider.
+var ErrNoKYCProvider = errors.New("no KYC status provider configured")
 
+// verifyKYCStatus resolves the user's current KYC status. The provider is
+// consulted even when the token claims a verified status, so a revocation
+// (which invalidates the provider's cache) applies before the token is
+// refreshed. The token's claim is only used when no provider is configured.
 func (m *Middlewares) verifyKYCStatus(ctx context.Context, userId string, ip string, tokenKycStatus bool, tokenCountry string) (bool, string, error) {
-	logger := m.Logger
+	if m.KYC == nil {
+		if tokenKycStatus && tokenCountry != "" {
+			return true, tokenCountry, nil
+		}
+		return false, "", ErrNoKYCProvider
+	}
 
-	if tokenKycStatus && tokenCountry != "" {
-		return true, tokenCountry, nil
+	status, err := m.KYC.Status(ctx, userId, ip)
+	if err != nil {
+		return false, "", err
 	}
+	return status.Verified, status.Country, nil
+}
 
-	cacheKey := fmt.Sprintf("kyc:status_country:%s", userId)
-	cachedStatus, err := m.Cache.Get(ctx, cacheKey)
-	var cacheData map[string]interface{}
-	if err == nil && cachedStatus != "" {
-		logger.Debug("KYC status found in cache", zap.String("userId", userId), zap.String("status", cachedStatus))
-		err = json.Unmarshal([]byte(cachedStatus), &cacheData)
+// claimTime reads a NumericDate claim. Claims are parsed with UseNumber so
+// numbers arrive as json.Number.
+func claimTime(claims jwt.MapClaims, key string) time.Time {
+	var secs int64
+	switch v := claims[key].(type) {
+	case json.Number:
+		f, err := v.Float64()
 		if err != nil {
-			logger.Error("Error unmarshalling cached KYC status", zap.Error(err))
-			return false, "", fmt.Errorf("unmarshal cached KYC status: %w", err)
-		}
-		kycStatus, ok := cacheData["kycStatus"].(bool)
-		if !ok {
-			return false, "", fmt.Errorf("cached KYC status is not a boolean")
+			return time.Time{}
 		}
-		country, ok := cacheData["kycCountry"].(string)
-		if !ok {
-			return false, "", fmt.Errorf("cached KYC country is not a string")
-		}
-		return kycStatus, country, nil
+		secs = int64(f)
+	case float64:
+		secs = int64(v)
+	default:
+		return time.Time{}
 	}
+	return time.Unix(secs, 0)
+}
 
-	logger.Debug("KYC status not in cache, calling API", zap.String("userId", userId))
-	baseURL := config.GetServiceURL("kycService")
-	url := fmt.Sprintf("%s/kyc/getKycStatusAndCountry", baseURL)
-
-	client := resty.New().
-		SetTimeout(10*time.Second).
-		SetHeader("Content-Type", "application/json")
-
-	var kycResponse KYCAPIResponse
+// isTokenRevoked checks the token against the revocation denylist and the
+// user's logout watermark. Lookup errors fail open so a Redis outage does
+// not log every user out.
+func (m *Middlewares) isTokenRevoked(ctx context.Context, claims jwt.MapClaims, userId string) bool {
+	if m.Revocation == nil {
+		return false
+	}
+	jti, _ := claims["jti"].(string)
+	revoked, err := m.Revocation.IsRevoked(ctx, jti, userId, claimTime(claims, "iat"))
+	if err != nil {
+		logs.WithContext(ctx).Error("Error checking token revocation", zap.String("userId", userId), zap.Error(err))
+		return false
+	}
+	if revoked {
+		logs.WithContext(ctx).Info("Rejected revoked access token", zap.String("userId", userId), zap.String("jti", jti))
+	}
+	return revoked
+}
 
-	resp, err := client.R().
-		SetContext(ctx).
-		SetQueryParam("id", userId).
-		SetQueryParam("ip", ip).
-		SetResult(&kycResponse).
-		Get(url)
+// ErrTokenRevoked and ErrTokenMissingUser complete the rejection reasons
+// above for checks done after the signature is verified.
+var (
+	ErrTokenRevoked     = errors.New("token has been revoked")
+	ErrTokenMissingUser = errors.New("token has no usable data claim")
+)
 
-	if err != nil {
-		logger.Error("Request to KYC service failed", zap.String("url", url), zap.String("userId", userId), zap.Error(err))
-		return false, "",fmt.Errorf("request failed: %w", err)
+// authenticateToken verifies accessToken and resolves the KYC-enriched user
+// it carries. It is shared by the Gin middleware and the gRPC interceptors.
+func (m *Middlewares) authenticateToken(ctx context.Context, accessToken string, clientIP string) (UserDetails, jwt.MapClaims, error) {
+	token, sErr := verifyTokenSignature(ctx, accessToken)
+	if sErr != nil {
+		return UserDetails{}, nil, sErr.GetError()
 	}
 
-	if !resp.IsSuccess() {
-		logger.Error("HTTP error from KYC service", zap.Int("statusCode", resp.StatusCode()), zap.String("body", resp.String()))
-		return false, "",fmt.Errorf("HTTP %d: %s", resp.StatusCode(), resp.String())
+	jwtClaims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return UserDetails{}, nil, ErrTokenMalformed
 	}
 
-	kycData := kycResponse.Data
-	status, statusOk := kycData["status"].(string)
-	verified, verifiedOk := kycData["verified"].(string)
-	var kycCountry string
-	isVerified := false
-	if (statusOk && status == "completed") || (verifiedOk && verified == "completed") {
-		isVerified = true
-		kycedUser, _ := kycData["user"].(map[string]interface{})
-		kycCountry, _ = kycedUser["country"].(string)
-		logger.Info("KYC verified user", zap.String("userId", userId))
-	} else {
-		logger.Info("KYC not verified", zap.String("userId", userId), zap.String("status", status), zap.String("verified", verified))
+	claimsData, ok := jwtClaims["data"]
+	if !ok {
+		return UserDetails{}, nil, ErrTokenMissingUser
 	}
 
-	var cacheDuration time.Duration
-	if isVerified {
-		cacheDuration = 2 * time.Hour
-	} else {
-		cacheDuration = 5 * time.Minute
+	jsonBytes, err := json.Marshal(claimsData)
+	if err != nil {
+		return UserDetails{}, nil, ErrTokenMissingUser
 	}
 
-	newCache := map[string]interface{}{
-		"kycStatus":  isVerified,
-		"kycCountry": kycCountry,
+	var user UserDetails
+	if err := json.Unmarshal(jsonBytes, &user); err != nil {
+		return UserDetails{}, nil, ErrTokenMissingUser
 	}
 
-	cacheStr, err := json.Marshal(newCache)
-	if err != nil {
-		logger.Error("Error marshalling KYC cache", zap.Error(err))
-		return false, "", fmt.Errorf("marshal KYC cache: %w", err)
+	if m.isTokenRevoked(ctx, jwtClaims, user.Id) {
+		return UserDetails{}, nil, ErrTokenRevoked
 	}
 
-	err = m.Cache.Set(ctx, cacheKey, string(cacheStr), cacheDuration)
+	// If the lookup fails the user keeps the KYC status and country their
+	// token was issued with.
+	verifiedKycStatus, country, err := m.verifyKYCStatus(ctx, user.Id, clientIP, user.KycStatus, user.KycCountry)
 	if err != nil {
-		logger.Error("Error caching KYC status", zap.Error(err))
-		// Don't fail the request if caching fails
+		m.Logger.Error("Error verifying KYC status", zap.String("userId", user.Id), zap.Error(err))
+	} else {
+		user.KycStatus = verifiedKycStatus
+		user.KycCountry = country
 	}
 
-	logger.Info("KYC status fetched and cached",
-		zap.String("userId", userId),
-		zap.Bool("kycStatus", isVerified),
-		zap.Duration("cacheDuration", cacheDuration))
+	return user, jwtClaims, nil
+}
+
+// withAuthenticatedUser stores everything later middleware and outbound
+// clients need about the authenticated caller.
+func withAuthenticatedUser(ctx context.Context, user UserDetails, claims jwt.MapClaims, accessToken string) context.Context {
+	ctx = context.WithValue(ctx, userContextKey, user)
+	ctx = context.WithValue(ctx, claimsContextKey, claims)
+	return context.WithValue(ctx, accessTokenContextKey, accessToken)
+}
 
-	return isVerified, kycCountry, nil
+// AccessTokenFromContext returns the raw access token that authenticated the
+// request, for forwarding to downstream services.
+func AccessTokenFromContext(ctx context.Context) string {
+	token, _ := ctx.Value(accessTokenContextKey).(string)
+	return token
 }
 
+// AuthenticateUser verifies the access token and stores the KYC-enriched
+// user in the request context. Tokens holding the impersonation scope may
+// send ImpersonationHeader to act as another user; see GetImpersonator.
 func (m *Middlewares) AuthenticateUser(c *gin.Context) {
 	accessToken := c.Request.Header.Get("Authorization")
 	if accessToken == "" {
@@ -198,50 +401,18 @@ func (m *Middlewares) AuthenticateUser(c *gin.Context) {
 		return
 	}
 
-	token, sErr := verifyTokenSignature(c.Request.Context(), accessToken)
-	if sErr != nil {
-		c.AbortWithStatus(http.StatusUnauthorized)
-		return
-	}
-
-	jwtClaims, ok := token.Claims.(jwt.MapClaims)
-	if !ok {
-		c.AbortWithStatus(http.StatusUnauthorized)
-		return
-	}
-
-	claimsData, ok := jwtClaims["data"]
-	if !ok {
-		c.AbortWithStatus(http.StatusUnauthorized)
-		return
-	}
-
-	jsonBytes, err := json.Marshal(claimsData)
+	user, jwtClaims, err := m.authenticateToken(c.Request.Context(), accessToken, c.ClientIP())
 	if err != nil {
 		c.AbortWithStatus(http.StatusUnauthorized)
 		return
 	}
 
-	var user UserDetails
-	err = json.Unmarshal(jsonBytes, &user)
-	if err != nil {
-		c.AbortWithStatus(http.StatusUnauthorized)
+	if c.GetHeader(ImpersonationHeader) != "" {
+		m.impersonate(c, user, jwtClaims, accessToken)
 		return
 	}
 
-	clientIP := c.ClientIP()
-
-	verifiedKycStatus, country, err := m.verifyKYCStatus(c.Request.Context(), user.Id, clientIP, user.KycStatus, user.KycCountry)
-	if err != nil {
-		m.Logger.Error("Error verifying KYC status", zap.String("userId", user.Id), zap.Error(err))
-		verifiedKycStatus = user.KycStatus
-	}
-
-	user.KycStatus = verifiedKycStatus
-	user.KycCountry = country
-
-	ctx := context.WithValue(c.Request.Context(), userContextKey, user)
-	c.Request = c.Request.WithContext(ctx)
+	c.Request = c.Request.WithContext(withAuthenticatedUser(c.Request.Context(), user, jwtClaims, accessToken))
 	c.Next()
 }
 
@@ -261,46 +432,12 @@ func (m *Middlewares) AuthenticateUserOptional(c *gin.Context) {
 		return
 	}
 
-	token, sErr := verifyTokenSignature(c.Request.Context(), accessToken)
-	if sErr != nil {
-		c.Next()
-		return
-	}
-
-	jwtClaims, ok := token.Claims.(jwt.MapClaims)
-	if !ok {
-		c.Next()
-		return
-	}
-
-	claimsData, ok := jwtClaims["data"]
-	if !ok {
-		c.Next()
-		return
-	}
-
-	jsonBytes, err := json.Marshal(claimsData)
+	user, jwtClaims, err := m.authenticateToken(c.Request.Context(), accessToken, c.ClientIP())
 	if err != nil {
 		c.Next()
 		return
 	}
 
-	var user UserDetails
-	if err := json.Unmarshal(jsonBytes, &user); err != nil {
-		c.Next()
-		return
-	}
-
-	clientIP := c.ClientIP()
-	verifiedKycStatus, country, err := m.verifyKYCStatus(c.Request.Context(), user.Id, clientIP, user.KycStatus, user.KycCountry)
-	if err != nil {
-		m.Logger.Error("Error verifying KYC status (optional auth)", zap.String("userId", user.Id), zap.Error(err))
-		verifiedKycStatus = user.KycStatus
-	}
-	user.KycStatus = verifiedKycStatus
-	user.KycCountry = country
-
-	ctx := context.WithValue(c.Request.Context(), userContextKey, user)
-	c.Request = c.Request.WithContext(ctx)
+	c.Request = c.Request.WithContext(withAuthenticatedUser(c.Request.Context(), user, jwtClaims, accessToken))
 	c.Next()
 }
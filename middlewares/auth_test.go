@@ -0,0 +1,165 @@
+package middlewares
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"go.uber.org/zap"
+
+	"github.com/Faze-Technologies/go-utils/cache"
+	"github.com/Faze-Technologies/go-utils/config"
+	"github.com/Faze-Technologies/go-utils/kyc"
+	"github.com/Faze-Technologies/go-utils/logs"
+)
+
+func TestValidateClaims(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	cfg := tokenValidationConfig{
+		issuer:    "auth-service",
+		audiences: []string{"api", "admin"},
+		leeway:    30 * time.Second,
+	}
+	base := func() jwt.MapClaims {
+		return jwt.MapClaims{
+			"exp": float64(now.Add(time.Hour).Unix()),
+			"iat": float64(now.Unix()),
+			"iss": "auth-service",
+			"aud": "api",
+		}
+	}
+	for _, tt := range []struct {
+		name   string
+		mutate func(jwt.MapClaims)
+		want   error
+	}{
+		{"valid", func(jwt.MapClaims) {}, nil},
+		{"missing exp", func(c jwt.MapClaims) { delete(c, "exp") }, ErrTokenMissingExpiry},
+		{"expired", func(c jwt.MapClaims) { c["exp"] = float64(now.Add(-time.Minute).Unix()) }, ErrTokenExpired},
+		{"expired within leeway", func(c jwt.MapClaims) { c["exp"] = float64(now.Add(-10 * time.Second).Unix()) }, nil},
+		{"not valid yet", func(c jwt.MapClaims) { c["nbf"] = float64(now.Add(time.Minute).Unix()) }, ErrTokenNotValidYet},
+		{"issued in future within leeway", func(c jwt.MapClaims) { c["iat"] = float64(now.Add(10 * time.Second).Unix()) }, nil},
+		{"issued in future", func(c jwt.MapClaims) { c["iat"] = float64(now.Add(time.Minute).Unix()) }, ErrTokenUsedBeforeIssued},
+		{"wrong issuer", func(c jwt.MapClaims) { c["iss"] = "someone-else" }, ErrTokenInvalidIssuer},
+		{"missing audience", func(c jwt.MapClaims) { delete(c, "aud") }, ErrTokenInvalidAudience},
+		{"audience list", func(c jwt.MapClaims) { c["aud"] = []interface{}{"other", "admin"} }, nil},
+	} {
+		claims := base()
+		tt.mutate(claims)
+		if got := validateClaims(claims, cfg, now); !errors.Is(got, tt.want) {
+			t.Errorf("%s: validateClaims() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestVerifyTokenSignature(t *testing.T) {
+	logs.NewLogger()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pubDER, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config.Set("auth_public_key", string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pubDER})))
+	t.Cleanup(func() { config.Set("auth_public_key", nil) })
+
+	exp := time.Now().Add(time.Hour).Unix()
+	sign := func(method jwt.SigningMethod, signingKey interface{}) string {
+		s, err := jwt.NewWithClaims(method, jwt.MapClaims{"exp": exp}).SignedString(signingKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return s
+	}
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range []struct {
+		name  string
+		token string
+		want  error
+	}{
+		{"valid RS256", sign(jwt.SigningMethodRS256, key), nil},
+		{"RS512 not allowed", sign(jwt.SigningMethodRS512, key), ErrTokenAlgorithm},
+		{"alg none", sign(jwt.SigningMethodNone, jwt.UnsafeAllowNoneSignatureType), ErrTokenAlgorithm},
+		{"HS256 not allowed", sign(jwt.SigningMethodHS256, []byte("secret")), ErrTokenAlgorithm},
+		{"wrong key", sign(jwt.SigningMethodRS256, otherKey), ErrTokenSignatureInvalid},
+		{"garbage", "not-a-token", ErrTokenMalformed},
+	} {
+		_, sErr := verifyTokenSignature(context.Background(), tt.token)
+		if tt.want == nil {
+			if sErr != nil {
+				t.Errorf("%s: unexpected error %v", tt.name, sErr.GetError())
+			}
+			continue
+		}
+		if sErr == nil {
+			t.Errorf("%s: want %v, got nil", tt.name, tt.want)
+			continue
+		}
+		if !errors.Is(sErr.GetError(), tt.want) {
+			t.Errorf("%s: reason = %v, want %v", tt.name, sErr.GetError(), tt.want)
+		}
+		if sErr.Message != "Invalid Access Token" {
+			t.Errorf("%s: client message leaks reason: %q", tt.name, sErr.Message)
+		}
+	}
+}
+
+func TestVerifyKYCStatusWithoutProvider(t *testing.T) {
+	m := &Middlewares{}
+	if verified, country, err := m.verifyKYCStatus(context.Background(), "u1", "", true, "IN"); err != nil || !verified || country != "IN" {
+		t.Errorf("verifyKYCStatus() = %v, %q, %v, want the token's status", verified, country, err)
+	}
+	if _, _, err := m.verifyKYCStatus(context.Background(), "u1", "", false, ""); !errors.Is(err, ErrNoKYCProvider) {
+		t.Errorf("verifyKYCStatus() = %v, want %v", err, ErrNoKYCProvider)
+	}
+}
+
+type kycProviderFunc func(userId string) (kyc.Status, error)
+
+func (f kycProviderFunc) Status(_ context.Context, userId string, _ string) (kyc.Status, error) {
+	return f(userId)
+}
+
+func TestVerifyKYCStatusRevoked(t *testing.T) {
+	// The token was issued while the user was verified; KYC has since been
+	// revoked and the provider's cache invalidated.
+	m := &Middlewares{KYC: kycProviderFunc(func(string) (kyc.Status, error) {
+		return kyc.Status{}, nil
+	})}
+	verified, country, err := m.verifyKYCStatus(context.Background(), "u1", "", true, "IN")
+	if err != nil || verified || country != "" {
+		t.Errorf("verifyKYCStatus() = %v, %q, %v, want the revoked status", verified, country, err)
+	}
+
+	m.KYC = kycProviderFunc(func(string) (kyc.Status, error) {
+		return kyc.Status{}, kyc.ErrCircuitOpen
+	})
+	if _, _, err := m.verifyKYCStatus(context.Background(), "u1", "", true, "IN"); !errors.Is(err, kyc.ErrCircuitOpen) {
+		t.Errorf("verifyKYCStatus() = %v, want the lookup error so the caller keeps the token's status", err)
+	}
+}
+
+func TestInitializeMiddlewaresRevocationOptIn(t *testing.T) {
+	logs.NewLogger()
+	t.Cleanup(func() { config.Set("auth.revocation.enabled", nil) })
+
+	if m := InitializeMiddlewares(&cache.Cache{}, zap.NewNop()); m.Revocation != nil {
+		t.Error("revocation enabled without auth.revocation.enabled")
+	}
+	config.Set("auth.revocation.enabled", true)
+	if m := InitializeMiddlewares(&cache.Cache{}, zap.NewNop()); m.Revocation == nil {
+		t.Error("auth.revocation.enabled did not enable revocation")
+	}
+}
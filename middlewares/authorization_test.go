@@ -0,0 +1,85 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Faze-Technologies/go-utils/logs"
+	"github.com/gin-gonic/gin"
+)
+
+func TestPolicies(t *testing.T) {
+	user := &UserDetails{
+		Id:       "u1",
+		Segments: []string{"vip"},
+		Metadata: map[string]interface{}{
+			"scopes": "wallet:read wallet:write",
+			"roles":  []interface{}{"support"},
+		},
+	}
+	cases := []struct {
+		policy Policy
+		want   bool
+	}{
+		{HasSegment("vip"), true},
+		{HasSegment("staff"), false},
+		{HasScope("wallet:write"), true},
+		{HasRole("support"), true},
+		{HasRole("admin"), false},
+		{AllOf(HasSegment("vip"), HasScope("wallet:read")), true},
+		{AllOf(HasSegment("vip"), HasRole("admin")), false},
+		{AnyOf(HasRole("admin"), HasRole("support")), true},
+		{AnyOf(HasRole("admin"), HasSegment("staff")), false},
+		{HasRole("admin").Or(HasSegment("vip").And(HasScope("wallet:write"))), true},
+		{Not(HasRole("admin")), true},
+		{AllOf(), true},
+		{AnyOf(), false},
+	}
+	for _, tc := range cases {
+		if got := tc.policy.Evaluate(user); got != tc.want {
+			t.Errorf("%s: Evaluate() = %v, want %v", tc.policy, got, tc.want)
+		}
+	}
+	if HasSegment("vip").Evaluate(nil) {
+		t.Error("a nil user satisfied a policy")
+	}
+	if got, want := AllOf(HasSegment("vip"), AnyOf(HasRole("a"), HasRole("b"))).String(), "(segment:vip AND (role:a OR role:b))"; got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+}
+
+func TestAuthorize(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	logs.NewLogger()
+
+	var user *UserDetails
+	r := gin.New()
+	r.GET("/admin", func(c *gin.Context) {
+		if user != nil {
+			c.Request = c.Request.WithContext(ContextWithUser(c.Request.Context(), *user))
+		}
+	}, Authorize(HasRole("admin")), func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	cases := []struct {
+		name   string
+		user   *UserDetails
+		status int
+	}{
+		{"anonymous", nil, http.StatusUnauthorized},
+		{"denied", &UserDetails{Id: "u1"}, http.StatusForbidden},
+		{"allowed", &UserDetails{Id: "u2", Metadata: map[string]interface{}{"roles": []interface{}{"admin"}}}, http.StatusOK},
+	}
+	for _, tc := range cases {
+		user = tc.user
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/admin", nil))
+		if w.Code != tc.status {
+			t.Errorf("%s: status = %d, want %d", tc.name, w.Code, tc.status)
+		}
+		if strings.Contains(w.Body.String(), "role:admin") {
+			t.Errorf("%s: response leaks the policy: %s", tc.name, w.Body.String())
+		}
+	}
+}
@@ -0,0 +1,185 @@
+package middlewares
+
+import (
+	"bytes"
+	"io"
+	"math/rand"
+	"net/http"
+	"strings"
+
+	"github.com/Faze-Technologies/go-utils/config"
+	"github.com/Faze-Technologies/go-utils/redact"
+	"github.com/gin-gonic/gin"
+)
+
+// Body capture defaults, overridable under logging.capture.
+const (
+	defaultCaptureMaxBytes    = 16 * 1024
+	defaultCaptureSamplePct   = 10
+	captureTruncationMarker   = "...[truncated]"
+	eventStreamContentType    = "text/event-stream"
+	captureDisabledContextKey = "bodyCaptureDisabled"
+)
+
+var defaultCaptureContentTypes = []string{
+	"application/json",
+	"application/x-www-form-urlencoded",
+	"text/plain",
+}
+
+// bodyCaptureConfig controls what GinLogger copies into span events:
+//
+//	logging:
+//	  capture:
+//	    max_bytes: 16384
+//	    sample_percent: 10          # of successful requests; errors are always captured
+//	    content_types: [application/json]
+//	    disabled_routes: ["POST /v1/documents/upload"]
+//	    always_routes: ["/v1/payments/:id"]
+//
+// Routes are matched against c.FullPath(), optionally prefixed by the method.
+type bodyCaptureConfig struct {
+	maxBytes       int
+	samplePercent  int
+	contentTypes   []string
+	disabledRoutes []string
+	alwaysRoutes   []string
+}
+
+func loadBodyCaptureConfig() bodyCaptureConfig {
+	cfg := bodyCaptureConfig{
+		maxBytes:       defaultCaptureMaxBytes,
+		samplePercent:  defaultCaptureSamplePct,
+		contentTypes:   config.GetSlice("logging.capture.content_types"),
+		disabledRoutes: config.GetSlice("logging.capture.disabled_routes"),
+		alwaysRoutes:   config.GetSlice("logging.capture.always_routes"),
+	}
+	if config.Get("logging.capture.max_bytes") != nil {
+		cfg.maxBytes = config.GetInt("logging.capture.max_bytes")
+	}
+	if config.Get("logging.capture.sample_percent") != nil {
+		cfg.samplePercent = config.GetInt("logging.capture.sample_percent")
+	}
+	if len(cfg.contentTypes) == 0 {
+		cfg.contentTypes = defaultCaptureContentTypes
+	}
+	return cfg
+}
+
+func routeListed(c *gin.Context, routes []string) bool {
+	route := c.FullPath()
+	return containsString(routes, route) || containsString(routes, c.Request.Method+" "+route)
+}
+
+func (cfg bodyCaptureConfig) allowsContentType(contentType string) bool {
+	if contentType == "" || strings.Contains(contentType, eventStreamContentType) {
+		return false
+	}
+	for _, ct := range cfg.contentTypes {
+		if strings.Contains(contentType, ct) {
+			return true
+		}
+	}
+	return false
+}
+
+// DisableBodyCapture stops GinLogger from capturing the response body of
+// routes it is attached to. Request bodies are read before route middleware
+// runs, so use logging.capture.disabled_routes to exclude those too.
+func DisableBodyCapture(c *gin.Context) {
+	c.Set(captureDisabledContextKey, true)
+	c.Next()
+}
+
+// bodyCapture is the per-request capture state.
+type bodyCapture struct {
+	cfg      bodyCaptureConfig
+	enabled  bool
+	sampled  bool
+	request  []byte
+	reqTrunc bool
+}
+
+// newBodyCapture decides whether the request takes part in capture and, if
+// so, buffers at most maxBytes of its body. The handler still reads the full
+// body: the buffered prefix is stitched back in front of the unread rest.
+func newBodyCapture(c *gin.Context, cfg bodyCaptureConfig) *bodyCapture {
+	bc := &bodyCapture{cfg: cfg}
+	if cfg.maxBytes <= 0 || routeListed(c, cfg.disabledRoutes) {
+		return bc
+	}
+	bc.enabled = true
+	bc.sampled = routeListed(c, cfg.alwaysRoutes) || rand.Intn(100) < cfg.samplePercent
+
+	if c.Request.Body == nil || c.Request.Body == http.NoBody || !cfg.allowsContentType(c.Request.Header.Get("Content-Type")) {
+		return bc
+	}
+	prefix, _ := io.ReadAll(io.LimitReader(c.Request.Body, int64(cfg.maxBytes)+1))
+	c.Request.Body = readCloser{
+		Reader: io.MultiReader(bytes.NewReader(prefix), c.Request.Body),
+		Closer: c.Request.Body,
+	}
+	if len(prefix) > cfg.maxBytes {
+		prefix, bc.reqTrunc = prefix[:cfg.maxBytes], true
+	}
+	bc.request = prefix
+	return bc
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// shouldReport reports whether captured bodies go on the span: always for
+// errors, for the sampled share of everything else.
+func (bc *bodyCapture) shouldReport(statusCode int) bool {
+	return bc.enabled && (bc.sampled || statusCode >= 400)
+}
+
+// renderCapturedBody redacts a captured body, falling back to fragment
+// redaction by content type when the body was cut off and no longer parses.
+func renderCapturedBody(redactor *redact.Redactor, body []byte, truncated bool, contentType string) string {
+	if !truncated {
+		return redactor.Body(body, contentType)
+	}
+	return redactor.Truncated(body, contentType) + captureTruncationMarker
+}
+
+// captureWriter tees at most maxBytes of the response body. It only buffers
+// once the status and content type are known to be worth keeping, and never
+// buffers event streams so SSE flushes straight through.
+type captureWriter struct {
+	gin.ResponseWriter
+	capture   *bodyCapture
+	c         *gin.Context
+	body      bytes.Buffer
+	truncated bool
+}
+
+func (w *captureWriter) shouldBuffer() bool {
+	if !w.capture.enabled || w.c.GetBool(captureDisabledContextKey) {
+		return false
+	}
+	if !w.capture.sampled && w.ResponseWriter.Status() < 400 {
+		return false
+	}
+	return w.capture.cfg.allowsContentType(w.ResponseWriter.Header().Get("Content-Type"))
+}
+
+func (w *captureWriter) Write(b []byte) (int, error) {
+	if !w.truncated && w.shouldBuffer() {
+		room := w.capture.cfg.maxBytes - w.body.Len()
+		if len(b) > room {
+			w.body.Write(b[:room])
+			w.truncated = true
+		} else {
+			w.body.Write(b)
+		}
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *captureWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
@@ -0,0 +1,175 @@
+package middlewares
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Faze-Technologies/go-utils/config"
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// setCaptureConfig overrides logging.capture.* for one test. Sampling is off
+// unless the test sets it, so only errors and always_routes are captured.
+func setCaptureConfig(t *testing.T, values map[string]interface{}) {
+	t.Helper()
+	if _, ok := values["sample_percent"]; !ok {
+		values["sample_percent"] = 0
+	}
+	for k, v := range values {
+		key := "logging.capture." + k
+		config.Set(key, v)
+		t.Cleanup(func() { config.Set(key, nil) })
+	}
+}
+
+// echo replies with status and the request body as JSON, and records what the
+// handler read so tests can check capture left the body intact.
+func echo(status int, read *string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		b, _ := io.ReadAll(c.Request.Body)
+		*read = string(b)
+		c.Data(status, "application/json", b)
+	}
+}
+
+func jsonRequest(path, body string) *http.Request {
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	return req
+}
+
+func TestBodyCaptureTruncation(t *testing.T) {
+	setCaptureConfig(t, map[string]interface{}{"max_bytes": 8})
+	body := `{"name":"abcdefghijklmnop"}`
+	var read string
+	span, w := serveLogged(t, func(r *gin.Engine) {
+		r.POST("/echo", echo(http.StatusBadRequest, &read))
+	}, jsonRequest("/echo", body))
+
+	if read != body {
+		t.Errorf("handler read %q, want the full body %q", read, body)
+	}
+	if w.Body.String() != body {
+		t.Errorf("response = %q, want the full body %q", w.Body.String(), body)
+	}
+	for _, key := range []string{"http.request.body", "http.response.body"} {
+		got, ok := spanValue(span, attribute.Key(key))
+		if !ok {
+			t.Fatalf("%s not recorded", key)
+		}
+		if !strings.HasSuffix(got, captureTruncationMarker) {
+			t.Errorf("%s = %q, want truncation marker", key, got)
+		}
+		if strings.Contains(got, "ijklmnop") {
+			t.Errorf("%s = %q, want at most max_bytes of the body", key, got)
+		}
+	}
+}
+
+func TestBodyCaptureContentTypes(t *testing.T) {
+	setCaptureConfig(t, map[string]interface{}{})
+	for _, tt := range []struct {
+		contentType string
+		captured    bool
+	}{
+		{"application/json; charset=utf-8", true},
+		{"text/plain", true},
+		{"application/octet-stream", false},
+		{"multipart/form-data; boundary=x", false},
+		{"", false},
+	} {
+		req := httptest.NewRequest(http.MethodPost, "/fail", strings.NewReader(`{"a":1}`))
+		if tt.contentType != "" {
+			req.Header.Set("Content-Type", tt.contentType)
+		}
+		span, _ := serveLogged(t, func(r *gin.Engine) {
+			r.POST("/fail", func(c *gin.Context) { c.Status(http.StatusBadRequest) })
+		}, req)
+
+		if _, ok := spanValue(span, "http.request.body"); ok != tt.captured {
+			t.Errorf("%q: request body captured = %v, want %v", tt.contentType, ok, tt.captured)
+		}
+	}
+}
+
+func TestBodyCaptureDisabledRoutes(t *testing.T) {
+	setCaptureConfig(t, map[string]interface{}{"disabled_routes": []string{"POST /config"}})
+	var read string
+	register := func(r *gin.Engine) {
+		r.POST("/config", echo(http.StatusBadRequest, &read))
+		r.POST("/middleware", DisableBodyCapture, echo(http.StatusBadRequest, &read))
+	}
+
+	span, _ := serveLogged(t, register, jsonRequest("/config", `{"a":1}`))
+	for _, key := range []string{"http.request.body", "http.response.body"} {
+		if got, ok := spanValue(span, attribute.Key(key)); ok {
+			t.Errorf("disabled_routes: %s = %q, want not captured", key, got)
+		}
+	}
+
+	// DisableBodyCapture runs after the request body is read, so only the
+	// response is excluded.
+	span, _ = serveLogged(t, register, jsonRequest("/middleware", `{"a":1}`))
+	if got, ok := spanValue(span, "http.response.body"); ok {
+		t.Errorf("DisableBodyCapture: http.response.body = %q, want not captured", got)
+	}
+	if read != `{"a":1}` {
+		t.Errorf("handler read %q, want the full body", read)
+	}
+}
+
+func TestBodyCaptureSampling(t *testing.T) {
+	setCaptureConfig(t, map[string]interface{}{"always_routes": []string{"/always"}})
+	for _, tt := range []struct {
+		path     string
+		status   int
+		captured bool
+	}{
+		{"/sometimes", http.StatusOK, false},
+		{"/sometimes", http.StatusUnprocessableEntity, true},
+		{"/sometimes", http.StatusInternalServerError, true},
+		{"/always", http.StatusOK, true},
+	} {
+		var read string
+		span, _ := serveLogged(t, func(r *gin.Engine) {
+			r.POST(tt.path, echo(tt.status, &read))
+		}, jsonRequest(tt.path, `{"a":1}`))
+
+		for _, key := range []string{"http.request.body", "http.response.body"} {
+			if _, ok := spanValue(span, attribute.Key(key)); ok != tt.captured {
+				t.Errorf("%s %d: %s captured = %v, want %v", tt.path, tt.status, key, ok, tt.captured)
+			}
+		}
+	}
+}
+
+func TestBodyCaptureEventStream(t *testing.T) {
+	setCaptureConfig(t, map[string]interface{}{
+		"always_routes": []string{"/events"},
+		"content_types": []string{"application/json", "text/"},
+	})
+	span, w := serveLogged(t, func(r *gin.Engine) {
+		r.GET("/events", func(c *gin.Context) {
+			c.Header("Content-Type", "text/event-stream")
+			c.Status(http.StatusOK)
+			for _, msg := range []string{"data: one\n\n", "data: two\n\n"} {
+				c.Writer.WriteString(msg)
+				c.Writer.Flush()
+			}
+		})
+	}, httptest.NewRequest(http.MethodGet, "/events", nil))
+
+	if got, want := w.Body.String(), "data: one\n\ndata: two\n\n"; got != want {
+		t.Errorf("stream = %q, want %q", got, want)
+	}
+	if !w.Flushed {
+		t.Error("stream was not flushed through the capture writer")
+	}
+	if got, ok := spanValue(span, "http.response.body"); ok {
+		t.Errorf("http.response.body = %q, want event streams never captured", got)
+	}
+}
@@ -0,0 +1,175 @@
+package middlewares
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/Faze-Technologies/go-utils/config"
+	"github.com/Faze-Technologies/go-utils/response"
+	"github.com/andybalholm/brotli"
+	"github.com/gin-gonic/gin"
+)
+
+// BodyLimitPolicy caps request bodies. Routes maps "METHOD /route/:param" or
+// "/route/:param" to a limit that replaces MaxBytes for that route, e.g. to
+// allow larger uploads.
+type BodyLimitPolicy struct {
+	MaxBytes int64
+	Routes   map[string]int64
+}
+
+// LoadBodyLimitPolicy reads a BodyLimitPolicy from config under prefix, e.g.
+// "body_limit":
+//
+//	"body_limit": {"max_bytes": 1048576, "routes": {"POST /v1/documents": 20971520}}
+//
+// MaxBytes defaults to 1 MiB.
+func LoadBodyLimitPolicy(prefix string) BodyLimitPolicy {
+	p := BodyLimitPolicy{
+		MaxBytes: defaultMaxBodyBytes,
+		Routes:   map[string]int64{},
+	}
+	if config.Get(prefix+".max_bytes") != nil {
+		p.MaxBytes = int64(config.GetInt(prefix + ".max_bytes"))
+	}
+	for route, limit := range config.GetMap(prefix + ".routes") {
+		switch n := limit.(type) {
+		case float64:
+			p.Routes[route] = int64(n)
+		case int:
+			p.Routes[route] = int64(n)
+		case int64:
+			p.Routes[route] = n
+		case string:
+			if parsed, err := strconv.ParseInt(strings.TrimSpace(n), 10, 64); err == nil {
+				p.Routes[route] = parsed
+			}
+		}
+	}
+	return p
+}
+
+// limitFor returns the limit for the request's route, preferring a
+// "METHOD /route" entry over a bare "/route" one. Viper lowercases map keys,
+// so routes are matched case-insensitively.
+func (p BodyLimitPolicy) limitFor(c *gin.Context) int64 {
+	route := strings.ToLower(c.FullPath())
+	qualified := strings.ToLower(c.Request.Method) + " " + route
+	limit := p.MaxBytes
+	for key, routeLimit := range p.Routes {
+		switch strings.ToLower(key) {
+		case qualified:
+			return routeLimit
+		case route:
+			limit = routeLimit
+		}
+	}
+	return limit
+}
+
+// LimitBody rejects request bodies over the route's limit with 413
+// PAYLOAD_TOO_LARGE. A declared Content-Length over the limit is rejected
+// before the handler runs; a chunked body is cut off at the limit, and the
+// handler's read fails with *http.MaxBytesError. Register it before
+// GinLogger so body capture reads through the limit:
+//
+//	r.Use(middlewares.LimitBody(middlewares.LoadBodyLimitPolicy("body_limit")))
+func LimitBody(policy BodyLimitPolicy) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		limit := policy.limitFor(c)
+		if limit <= 0 || c.Request.Body == nil || c.Request.Body == http.NoBody {
+			c.Next()
+			return
+		}
+		if c.Request.ContentLength > limit {
+			sendPayloadTooLarge(c, limit)
+			return
+		}
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, limit)
+		c.Next()
+	}
+}
+
+// MaxBodySize caps the body of a single route or group at n bytes. It can
+// only tighten a global LimitBody, not relax it; raise a route's limit in
+// BodyLimitPolicy.Routes instead.
+func MaxBodySize(n int64) gin.HandlerFunc {
+	return LimitBody(BodyLimitPolicy{MaxBytes: n})
+}
+
+// sendDecompressError answers 413 if the compressed body hit LimitBody and
+// 400 if it is corrupt.
+func sendDecompressError(c *gin.Context, encoding string, err error) {
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		sendPayloadTooLarge(c, maxBytesErr.Limit)
+		return
+	}
+	response.SendHTTPError(c, response.InvalidArgument("Malformed "+encoding+" request body"))
+	c.Abort()
+}
+
+// Decompress transparently inflates gzip and brotli request bodies, such as
+// batch uploads, so handlers see plain bytes. The decompressed body is
+// capped at the route's limit from policy; a body that inflates past it,
+// e.g. a zip bomb, is rejected with 413 without inflating the rest. Routes
+// without a limit are still capped at 1 MiB decompressed, since a few
+// kilobytes on the wire can inflate to gigabytes. Corrupt
+// bodies get 400 and other encodings 415. Register it after LimitBody, which
+// then caps the compressed size:
+//
+//	limits := middlewares.LoadBodyLimitPolicy("body_limit")
+//	r.Use(middlewares.LimitBody(limits), middlewares.Decompress(limits))
+func Decompress(policy BodyLimitPolicy) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		encoding := strings.ToLower(strings.TrimSpace(c.GetHeader("Content-Encoding")))
+		if encoding == "" || encoding == "identity" || c.Request.Body == nil || c.Request.Body == http.NoBody {
+			c.Next()
+			return
+		}
+
+		var reader io.Reader
+		switch encoding {
+		case encodingGzip, "x-gzip":
+			zr, err := gzip.NewReader(c.Request.Body)
+			if err != nil {
+				sendDecompressError(c, encoding, err)
+				return
+			}
+			defer zr.Close()
+			reader = zr
+		case encodingBrotli:
+			reader = brotli.NewReader(c.Request.Body)
+		default:
+			response.SendHTTPError(c, response.UnsupportedMediaType("Unsupported Content-Encoding "+encoding))
+			c.Abort()
+			return
+		}
+
+		limit := policy.limitFor(c)
+		if limit <= 0 {
+			limit = defaultMaxBodyBytes
+		}
+		body, err := io.ReadAll(io.LimitReader(reader, limit+1))
+		if err != nil {
+			sendDecompressError(c, encoding, err)
+			return
+		}
+		if int64(len(body)) > limit {
+			sendPayloadTooLarge(c, limit)
+			return
+		}
+
+		c.Request.Body.Close()
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+		c.Request.ContentLength = int64(len(body))
+		c.Request.Header.Set("Content-Length", strconv.Itoa(len(body)))
+		c.Request.Header.Del("Content-Encoding")
+		c.Next()
+	}
+}
@@ -0,0 +1,206 @@
+package middlewares
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mileusna/useragent"
+)
+
+// Headers the apps send to identify themselves.
+const (
+	AppVersionHeader = "appversion"
+	AppIDHeader      = "appid"
+	PlatformHeader   = "source"
+)
+
+// Platforms as sent in PlatformHeader.
+const (
+	PlatformAndroid = "android"
+	PlatformIOS     = "ios"
+	PlatformWeb     = "web"
+)
+
+// Device types reported in ClientInfo.DeviceType.
+const (
+	DeviceMobile  = "mobile"
+	DeviceTablet  = "tablet"
+	DeviceDesktop = "desktop"
+	DeviceBot     = "bot"
+)
+
+const clientInfoContextKey contextKey = "clientInfo"
+
+// AppVersion is a semantic version such as "5.12.1" or "v5.12.1-beta.2".
+// Missing minor and patch numbers are zero, and build metadata ("+1234")
+// is ignored.
+type AppVersion struct {
+	Major, Minor, Patch int
+	PreRelease          string
+}
+
+// ParseAppVersion parses a semantic version.
+func ParseAppVersion(s string) (AppVersion, error) {
+	var v AppVersion
+	raw := strings.TrimPrefix(strings.TrimSpace(s), "v")
+	raw, _, _ = strings.Cut(raw, "+")
+	raw, v.PreRelease, _ = strings.Cut(raw, "-")
+
+	parts := strings.Split(raw, ".")
+	if raw == "" || len(parts) > 3 {
+		return AppVersion{}, fmt.Errorf("invalid app version %q", s)
+	}
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return AppVersion{}, fmt.Errorf("invalid app version %q", s)
+		}
+		switch i {
+		case 0:
+			v.Major = n
+		case 1:
+			v.Minor = n
+		case 2:
+			v.Patch = n
+		}
+	}
+	return v, nil
+}
+
+// Compare returns -1, 0 or 1 as v is older than, equal to or newer than o.
+// A pre-release is older than the release it precedes, and pre-releases
+// compare by semver precedence, so beta.10 is newer than beta.2.
+func (v AppVersion) Compare(o AppVersion) int {
+	for _, d := range []int{v.Major - o.Major, v.Minor - o.Minor, v.Patch - o.Patch} {
+		if d < 0 {
+			return -1
+		}
+		if d > 0 {
+			return 1
+		}
+	}
+	switch {
+	case v.PreRelease == o.PreRelease:
+		return 0
+	case v.PreRelease == "":
+		return 1
+	case o.PreRelease == "":
+		return -1
+	}
+	return comparePreRelease(v.PreRelease, o.PreRelease)
+}
+
+// comparePreRelease compares dot-separated identifiers in turn: numeric ones
+// numerically and below alphanumeric ones, the rest lexically. When all
+// shared identifiers are equal, the one with more identifiers is newer.
+func comparePreRelease(a, b string) int {
+	as, bs := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(as) && i < len(bs); i++ {
+		an, aErr := strconv.Atoi(as[i])
+		bn, bErr := strconv.Atoi(bs[i])
+		switch {
+		case aErr == nil && bErr == nil:
+			if an != bn {
+				if an < bn {
+					return -1
+				}
+				return 1
+			}
+		case aErr == nil:
+			return -1
+		case bErr == nil:
+			return 1
+		default:
+			if c := strings.Compare(as[i], bs[i]); c != 0 {
+				return c
+			}
+		}
+	}
+	switch {
+	case len(as) < len(bs):
+		return -1
+	case len(as) > len(bs):
+		return 1
+	}
+	return 0
+}
+
+func (v AppVersion) String() string {
+	s := fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+	if v.PreRelease != "" {
+		s += "-" + v.PreRelease
+	}
+	return s
+}
+
+// ClientInfo describes the client behind a request, from the app headers
+// and the User-Agent.
+type ClientInfo struct {
+	// Platform is PlatformHeader lowercased. It is not guessed from the
+	// User-Agent, since a mobile browser and the app share an OS.
+	Platform string
+	AppID    string
+	// AppVersion is AppVersionHeader parsed; AppVersionValid is false when
+	// the header is missing or malformed. RawAppVersion is the header as
+	// sent.
+	AppVersion      AppVersion
+	RawAppVersion   string
+	AppVersionValid bool
+	OS              string
+	OSVersion       string
+	// Device is the device model when the User-Agent names one.
+	Device     string
+	DeviceType string
+	Browser    string
+	Bot        bool
+	UserAgent  string
+}
+
+func newClientInfo(c *gin.Context, ua useragent.UserAgent) ClientInfo {
+	info := ClientInfo{
+		Platform:      strings.ToLower(strings.TrimSpace(c.GetHeader(PlatformHeader))),
+		AppID:         strings.TrimSpace(c.GetHeader(AppIDHeader)),
+		RawAppVersion: strings.TrimSpace(c.GetHeader(AppVersionHeader)),
+		OS:            ua.OS,
+		OSVersion:     ua.OSVersion,
+		Device:        ua.Device,
+		Browser:       ua.Name,
+		Bot:           ua.Bot,
+		UserAgent:     ua.String,
+	}
+	if info.RawAppVersion != "" {
+		if v, err := ParseAppVersion(info.RawAppVersion); err == nil {
+			info.AppVersion, info.AppVersionValid = v, true
+		}
+	}
+
+	switch {
+	case ua.Bot:
+		info.DeviceType = DeviceBot
+	case ua.Tablet:
+		info.DeviceType = DeviceTablet
+	case ua.Mobile:
+		info.DeviceType = DeviceMobile
+	case ua.Desktop:
+		info.DeviceType = DeviceDesktop
+	}
+	return info
+}
+
+// ClientInfoFromContext returns the ClientInfo stored by ParseUserAgent.
+func ClientInfoFromContext(ctx context.Context) (ClientInfo, bool) {
+	info, ok := ctx.Value(clientInfoContextKey).(ClientInfo)
+	return info, ok
+}
+
+// GetClientInfo returns the request's ClientInfo, parsing the headers if
+// ParseUserAgent has not run.
+func GetClientInfo(c *gin.Context) ClientInfo {
+	if info, ok := ClientInfoFromContext(c.Request.Context()); ok {
+		return info
+	}
+	return newClientInfo(c, useragent.Parse(c.Request.UserAgent()))
+}
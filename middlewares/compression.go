@@ -0,0 +1,266 @@
+package middlewares
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/Faze-Technologies/go-utils/config"
+	"github.com/andybalholm/brotli"
+	"github.com/gin-gonic/gin"
+)
+
+// Compression defaults applied by LoadCompressionPolicy.
+const defaultCompressMinBytes = 1024
+
+var defaultCompressContentTypes = []string{
+	"application/json",
+	"application/javascript",
+	"application/xml",
+	"image/svg+xml",
+	"text/",
+}
+
+const (
+	encodingBrotli = "br"
+	encodingGzip   = "gzip"
+)
+
+// CompressionPolicy configures Compress. Responses shorter than MinBytes, or
+// whose Content-Type does not start with one of ContentTypes, are sent as is.
+type CompressionPolicy struct {
+	MinBytes     int
+	ContentTypes []string
+}
+
+// LoadCompressionPolicy reads a CompressionPolicy from config under prefix,
+// e.g. "compression":
+//
+//	"compression": {"min_bytes": 1024, "content_types": ["application/json", "text/"]}
+func LoadCompressionPolicy(prefix string) CompressionPolicy {
+	p := CompressionPolicy{
+		MinBytes:     defaultCompressMinBytes,
+		ContentTypes: config.GetSlice(prefix + ".content_types"),
+	}
+	if config.Get(prefix+".min_bytes") != nil {
+		p.MinBytes = config.GetInt(prefix + ".min_bytes")
+	}
+	if len(p.ContentTypes) == 0 {
+		p.ContentTypes = defaultCompressContentTypes
+	}
+	return p
+}
+
+func (p CompressionPolicy) allowsContentType(contentType string) bool {
+	if strings.HasPrefix(contentType, eventStreamContentType) {
+		return false
+	}
+	for _, ct := range p.ContentTypes {
+		if strings.HasPrefix(contentType, ct) {
+			return true
+		}
+	}
+	return false
+}
+
+// negotiateEncoding picks brotli over gzip from Accept-Encoding, honouring
+// q=0 exclusions. It returns "" when neither is acceptable.
+func negotiateEncoding(acceptEncoding string) string {
+	accepted := map[string]bool{}
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if parsed, err := strconv.ParseFloat(v, 64); err == nil {
+				q = parsed
+			}
+		}
+		accepted[name] = q > 0
+	}
+	for _, enc := range []string{encodingBrotli, encodingGzip} {
+		if ok, listed := accepted[enc]; (listed && ok) || (!listed && accepted["*"]) {
+			return enc
+		}
+	}
+	return ""
+}
+
+var (
+	gzipWriters = sync.Pool{New: func() any {
+		w, _ := gzip.NewWriterLevel(io.Discard, gzip.DefaultCompression)
+		return w
+	}}
+	brotliWriters = sync.Pool{New: func() any {
+		return brotli.NewWriterLevel(io.Discard, brotli.DefaultCompression)
+	}}
+)
+
+// resettableWriter is implemented by both gzip.Writer and brotli.Writer.
+type resettableWriter interface {
+	io.WriteCloser
+	Flush() error
+	Reset(io.Writer)
+}
+
+// Compress compresses responses with brotli or gzip according to the
+// client's Accept-Encoding:
+//
+//	r.Use(middlewares.Compress(middlewares.LoadCompressionPolicy("compression")))
+//
+// The first MinBytes of the response are buffered to decide, so small
+// responses skip compression. Responses that already have a
+// Content-Encoding, event streams and HEAD requests are never compressed.
+// Flush still works, so long polls and chunked JSON streams are fine.
+func Compress(policy CompressionPolicy) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Writer.Header().Add("Vary", "Accept-Encoding")
+		encoding := negotiateEncoding(c.GetHeader("Accept-Encoding"))
+		if encoding == "" || c.Request.Method == http.MethodHead {
+			c.Next()
+			return
+		}
+
+		original := c.Writer
+		cw := &compressWriter{ResponseWriter: original, policy: policy, encoding: encoding, status: http.StatusOK}
+		c.Writer = cw
+		defer func() {
+			cw.finish()
+			c.Writer = original
+		}()
+		c.Next()
+	}
+}
+
+// compressWriter buffers up to MinBytes before deciding whether to
+// compress, then streams either through the encoder or straight through.
+type compressWriter struct {
+	gin.ResponseWriter
+	policy   CompressionPolicy
+	encoding string
+
+	status  int
+	buf     bytes.Buffer
+	decided bool
+	encoder resettableWriter
+}
+
+func (w *compressWriter) WriteHeader(code int) {
+	if !w.decided {
+		w.status = code
+	}
+}
+
+func (w *compressWriter) WriteHeaderNow() {
+	w.decide()
+}
+
+func (w *compressWriter) Write(b []byte) (int, error) {
+	if !w.decided {
+		w.buf.Write(b)
+		if w.buf.Len() < w.policy.MinBytes {
+			return len(b), nil
+		}
+		if err := w.decide(); err != nil {
+			return 0, err
+		}
+		return len(b), nil
+	}
+	if w.encoder != nil {
+		return w.encoder.Write(b)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *compressWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+func (w *compressWriter) Status() int {
+	if !w.decided {
+		return w.status
+	}
+	return w.ResponseWriter.Status()
+}
+
+func (w *compressWriter) Written() bool {
+	return w.decided || w.buf.Len() > 0
+}
+
+func (w *compressWriter) Flush() {
+	w.decide()
+	if w.encoder != nil {
+		w.encoder.Flush()
+	}
+	w.ResponseWriter.Flush()
+}
+
+// decide sends the headers and the buffered bytes, through an encoder if
+// the response qualifies.
+func (w *compressWriter) decide() error {
+	if w.decided {
+		return nil
+	}
+	w.decided = true
+
+	header := w.ResponseWriter.Header()
+	contentType := header.Get("Content-Type")
+	if contentType == "" && w.buf.Len() > 0 {
+		contentType = http.DetectContentType(w.buf.Bytes())
+		header.Set("Content-Type", contentType)
+	}
+	// Byte ranges refer to the uncompressed representation, so partial
+	// content is sent as is.
+	if w.buf.Len() >= w.policy.MinBytes && w.buf.Len() > 0 &&
+		w.status != http.StatusNoContent && w.status != http.StatusNotModified &&
+		w.status != http.StatusPartialContent && header.Get("Content-Range") == "" &&
+		header.Get("Content-Encoding") == "" && w.policy.allowsContentType(contentType) {
+		header.Set("Content-Encoding", w.encoding)
+		header.Del("Content-Length")
+		if w.encoding == encodingBrotli {
+			w.encoder = brotliWriters.Get().(*brotli.Writer)
+		} else {
+			w.encoder = gzipWriters.Get().(*gzip.Writer)
+		}
+		w.encoder.Reset(w.ResponseWriter)
+	}
+
+	w.ResponseWriter.WriteHeader(w.status)
+	if w.buf.Len() == 0 {
+		w.ResponseWriter.WriteHeaderNow()
+		return nil
+	}
+	var err error
+	if w.encoder != nil {
+		_, err = w.encoder.Write(w.buf.Bytes())
+	} else {
+		_, err = w.ResponseWriter.Write(w.buf.Bytes())
+	}
+	w.buf.Reset()
+	return err
+}
+
+// finish flushes whatever is still buffered and returns the encoder to its
+// pool.
+func (w *compressWriter) finish() {
+	if !w.decided && w.buf.Len() == 0 && !w.ResponseWriter.Written() && w.status == http.StatusOK {
+		// Nothing was written; leave the response to gin's defaults.
+		return
+	}
+	w.decide()
+	if w.encoder == nil {
+		return
+	}
+	w.encoder.Close()
+	w.encoder.Reset(io.Discard)
+	if w.encoding == encodingBrotli {
+		brotliWriters.Put(w.encoder)
+	} else {
+		gzipWriters.Put(w.encoder)
+	}
+	w.encoder = nil
+}
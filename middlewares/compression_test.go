@@ -0,0 +1,154 @@
+package middlewares
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Faze-Technologies/go-utils/logs"
+	"github.com/andybalholm/brotli"
+	"github.com/gin-gonic/gin"
+)
+
+func TestNegotiateEncoding(t *testing.T) {
+	cases := map[string]string{
+		"":                    "",
+		"gzip, deflate":       "gzip",
+		"gzip, deflate, br":   "br",
+		"br;q=0, gzip;q=0.5":  "gzip",
+		"*":                   "br",
+		"*, br;q=0":           "gzip",
+		"identity":            "",
+		"GZIP;q=0.8, br;q=0":  "gzip",
+		"gzip;q=0, br;q=0, *": "",
+	}
+	for header, want := range cases {
+		if got := negotiateEncoding(header); got != want {
+			t.Errorf("negotiateEncoding(%q) = %q, want %q", header, got, want)
+		}
+	}
+}
+
+func TestCompress(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	large := strings.Repeat(`{"id":1,"name":"player"},`, 100)
+
+	r := gin.New()
+	r.Use(Compress(CompressionPolicy{MinBytes: 1024, ContentTypes: defaultCompressContentTypes}))
+	r.GET("/large", func(c *gin.Context) { c.Data(http.StatusOK, "application/json", []byte(large)) })
+	r.GET("/small", func(c *gin.Context) { c.JSON(http.StatusOK, gin.H{"ok": true}) })
+	r.GET("/image", func(c *gin.Context) { c.Data(http.StatusOK, "image/png", []byte(large)) })
+	r.GET("/created", func(c *gin.Context) { c.Status(http.StatusCreated) })
+	r.GET("/range", func(c *gin.Context) {
+		c.Header("Content-Range", fmt.Sprintf("bytes 0-%d/%d", len(large)-1, len(large)*2))
+		c.Data(http.StatusPartialContent, "application/json", []byte(large))
+	})
+
+	get := func(path, acceptEncoding string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("Accept-Encoding", acceptEncoding)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	w := get("/large", "gzip")
+	if w.Header().Get("Content-Encoding") != "gzip" {
+		t.Fatalf("large gzip: Content-Encoding %q", w.Header().Get("Content-Encoding"))
+	}
+	zr, err := gzip.NewReader(w.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if body, _ := io.ReadAll(zr); string(body) != large {
+		t.Fatal("gzip body does not round-trip")
+	}
+
+	w = get("/large", "gzip, br")
+	if w.Header().Get("Content-Encoding") != "br" {
+		t.Fatalf("large br: Content-Encoding %q", w.Header().Get("Content-Encoding"))
+	}
+	if body, _ := io.ReadAll(brotli.NewReader(w.Body)); string(body) != large {
+		t.Fatal("brotli body does not round-trip")
+	}
+
+	for _, path := range []string{"/small", "/image"} {
+		if w := get(path, "gzip"); w.Header().Get("Content-Encoding") != "" || w.Code != http.StatusOK {
+			t.Fatalf("%s: got %d Content-Encoding %q, want uncompressed 200", path, w.Code, w.Header().Get("Content-Encoding"))
+		}
+	}
+	if w := get("/range", "gzip"); w.Header().Get("Content-Encoding") != "" || w.Body.String() != large {
+		t.Fatalf("/range: Content-Encoding %q, want the partial content uncompressed", w.Header().Get("Content-Encoding"))
+	}
+	if w := get("/created", "gzip"); w.Code != http.StatusCreated {
+		t.Fatalf("/created: got %d, want 201", w.Code)
+	}
+}
+
+func TestBodyLimits(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	logs.NewLogger()
+
+	limits := BodyLimitPolicy{MaxBytes: 100, Routes: map[string]int64{"post /upload": 1000, "/upload": 200, "/unlimited": 0}}
+	r := gin.New()
+	r.Use(LimitBody(limits), Decompress(limits))
+	echo := func(c *gin.Context) {
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			c.Status(http.StatusBadRequest)
+			return
+		}
+		c.String(http.StatusOK, "%d", len(body))
+	}
+	r.POST("/echo", echo)
+	r.POST("/upload", echo)
+	r.POST("/unlimited", echo)
+
+	gzipped := func(n int) []byte {
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		zw.Write(bytes.Repeat([]byte("a"), n))
+		zw.Close()
+		return buf.Bytes()
+	}
+	post := func(path string, body []byte, encoding string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, path, bytes.NewReader(body))
+		if encoding != "" {
+			req.Header.Set("Content-Encoding", encoding)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	cases := []struct {
+		name     string
+		path     string
+		body     []byte
+		encoding string
+		status   int
+		echoed   string
+	}{
+		{"within limit", "/echo", bytes.Repeat([]byte("a"), 100), "", http.StatusOK, "100"},
+		{"over limit", "/echo", bytes.Repeat([]byte("a"), 101), "", http.StatusRequestEntityTooLarge, ""},
+		{"route override", "/upload", bytes.Repeat([]byte("a"), 500), "", http.StatusOK, "500"},
+		{"gzip inflated", "/echo", gzipped(100), "gzip", http.StatusOK, "100"},
+		// Small on the wire, but inflates past the limit.
+		{"gzip bomb", "/echo", gzipped(5000), "gzip", http.StatusRequestEntityTooLarge, ""},
+		{"corrupt gzip", "/echo", []byte("not gzip"), "gzip", http.StatusBadRequest, ""},
+		{"unknown encoding", "/echo", []byte("x"), "zstd", http.StatusUnsupportedMediaType, ""},
+		// No limit configured for the route, but inflation is still capped.
+		{"gzip bomb unlimited route", "/unlimited", gzipped(defaultMaxBodyBytes + 1), "gzip", http.StatusRequestEntityTooLarge, ""},
+	}
+	for _, tc := range cases {
+		w := post(tc.path, tc.body, tc.encoding)
+		if w.Code != tc.status || (tc.echoed != "" && w.Body.String() != tc.echoed) {
+			t.Errorf("%s: got %d %q, want %d %q", tc.name, w.Code, w.Body.String(), tc.status, tc.echoed)
+		}
+	}
+}
@@ -0,0 +1,164 @@
+package middlewares
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Faze-Technologies/go-utils/config"
+	"github.com/gin-gonic/gin"
+)
+
+// CORS defaults applied by LoadCORSPolicy when a list is not configured.
+var (
+	defaultCORSMethods = []string{
+		http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut,
+		http.MethodPatch, http.MethodDelete, http.MethodOptions,
+	}
+	defaultCORSHeaders = []string{
+		"Authorization", "Content-Type", "X-Request-Id", "X-Api-Key",
+		AppVersionHeader, AppIDHeader, PlatformHeader,
+	}
+	defaultCORSExposedHeaders = []string{"X-Request-Id"}
+)
+
+const defaultCORSMaxAge = 10 * time.Minute
+
+// CORSPolicy describes which browser origins may call the service.
+// AllowedOrigins entries are exact origins ("https://app.example.com"),
+// wildcard subdomains ("https://*.example.com", which does not match the
+// bare domain) or "*" for any origin. "*" cannot be combined with
+// AllowCredentials, since that would let any site make credentialed calls
+// on behalf of a signed-in user; see Validate.
+type CORSPolicy struct {
+	AllowedOrigins   []string
+	AllowedMethods   []string
+	AllowedHeaders   []string
+	ExposedHeaders   []string
+	AllowCredentials bool
+	MaxAge           time.Duration
+}
+
+// LoadCORSPolicy reads a CORSPolicy from config under prefix, e.g. "cors":
+//
+//	"cors": {"allowed_origins": ["https://*.example.com"], "allow_credentials": true,
+//	         "allowed_headers": [], "exposed_headers": [], "max_age_seconds": 600}
+//
+// Empty method and header lists fall back to the defaults above.
+func LoadCORSPolicy(prefix string) CORSPolicy {
+	p := CORSPolicy{
+		AllowedOrigins:   config.GetSlice(prefix + ".allowed_origins"),
+		AllowedMethods:   config.GetSlice(prefix + ".allowed_methods"),
+		AllowedHeaders:   config.GetSlice(prefix + ".allowed_headers"),
+		ExposedHeaders:   config.GetSlice(prefix + ".exposed_headers"),
+		AllowCredentials: config.GetBool(prefix + ".allow_credentials"),
+		MaxAge:           defaultCORSMaxAge,
+	}
+	if len(p.AllowedMethods) == 0 {
+		p.AllowedMethods = defaultCORSMethods
+	}
+	if len(p.AllowedHeaders) == 0 {
+		p.AllowedHeaders = defaultCORSHeaders
+	}
+	if len(p.ExposedHeaders) == 0 {
+		p.ExposedHeaders = defaultCORSExposedHeaders
+	}
+	if config.Get(prefix+".max_age_seconds") != nil {
+		p.MaxAge = time.Duration(config.GetInt(prefix+".max_age_seconds")) * time.Second
+	}
+	return p
+}
+
+// ErrCORSWildcardCredentials is returned by CORSPolicy.Validate for a policy
+// that allows any origin together with credentials.
+var ErrCORSWildcardCredentials = errors.New(`middlewares: CORS policy cannot allow origin "*" with credentials; list the origins instead`)
+
+// Validate rejects policies that would expose credentialed endpoints to
+// every site.
+func (p CORSPolicy) Validate() error {
+	if p.AllowCredentials && containsString(p.AllowedOrigins, "*") {
+		return ErrCORSWildcardCredentials
+	}
+	return nil
+}
+
+// originAllowed matches origin against the allow list, ignoring case.
+func (p CORSPolicy) originAllowed(origin string) bool {
+	origin = strings.ToLower(origin)
+	for _, allowed := range p.AllowedOrigins {
+		allowed = strings.ToLower(allowed)
+		if allowed == "*" || allowed == origin {
+			return true
+		}
+		scheme, host, ok := strings.Cut(allowed, "://*.")
+		if !ok {
+			continue
+		}
+		// "https://*.example.com" matches "https://a.example.com" and
+		// "https://a.b.example.com", but not "https://evil-example.com".
+		if strings.HasPrefix(origin, scheme+"://") && strings.HasSuffix(origin, "."+host) {
+			return true
+		}
+	}
+	return false
+}
+
+// CORS answers preflight requests and sets CORS headers on simple requests
+// according to policy. Requests from origins outside the allow list get no
+// CORS headers, so the browser blocks them; preflights from them are
+// rejected with 403. CORS panics if policy fails Validate, so a dangerous
+// config stops the service at boot instead of shipping.
+func CORS(policy CORSPolicy) gin.HandlerFunc {
+	if err := policy.Validate(); err != nil {
+		panic(err)
+	}
+	methods := strings.Join(policy.AllowedMethods, ", ")
+	headers := strings.Join(policy.AllowedHeaders, ", ")
+	exposed := strings.Join(policy.ExposedHeaders, ", ")
+	maxAge := strconv.Itoa(int(policy.MaxAge.Seconds()))
+
+	return func(c *gin.Context) {
+		origin := c.GetHeader("Origin")
+		if origin == "" {
+			c.Next()
+			return
+		}
+		c.Writer.Header().Add("Vary", "Origin")
+
+		preflight := c.Request.Method == http.MethodOptions && c.GetHeader("Access-Control-Request-Method") != ""
+		if !policy.originAllowed(origin) {
+			if preflight {
+				c.AbortWithStatus(http.StatusForbidden)
+				return
+			}
+			c.Next()
+			return
+		}
+
+		if containsString(policy.AllowedOrigins, "*") {
+			c.Header("Access-Control-Allow-Origin", "*")
+		} else {
+			c.Header("Access-Control-Allow-Origin", origin)
+		}
+		if policy.AllowCredentials {
+			c.Header("Access-Control-Allow-Credentials", "true")
+		}
+
+		if preflight {
+			c.Writer.Header().Add("Vary", "Access-Control-Request-Method")
+			c.Writer.Header().Add("Vary", "Access-Control-Request-Headers")
+			c.Header("Access-Control-Allow-Methods", methods)
+			c.Header("Access-Control-Allow-Headers", headers)
+			c.Header("Access-Control-Max-Age", maxAge)
+			c.AbortWithStatus(http.StatusNoContent)
+			return
+		}
+
+		if exposed != "" {
+			c.Header("Access-Control-Expose-Headers", exposed)
+		}
+		c.Next()
+	}
+}
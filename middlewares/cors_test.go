@@ -0,0 +1,78 @@
+package middlewares
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestCORS(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	policy := CORSPolicy{
+		AllowedOrigins:   []string{"https://app.example.com", "https://*.partner.io"},
+		AllowedMethods:   defaultCORSMethods,
+		AllowedHeaders:   defaultCORSHeaders,
+		AllowCredentials: true,
+		MaxAge:           defaultCORSMaxAge,
+	}
+	r := gin.New()
+	r.Use(CORS(policy))
+	r.GET("/x", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	for _, tt := range []struct {
+		name       string
+		method     string
+		origin     string
+		wantStatus int
+		wantAllow  string
+	}{
+		{"exact origin", http.MethodGet, "https://app.example.com", http.StatusOK, "https://app.example.com"},
+		{"wildcard subdomain", http.MethodGet, "https://a.b.partner.io", http.StatusOK, "https://a.b.partner.io"},
+		{"wildcard excludes bare domain", http.MethodGet, "https://partner.io", http.StatusOK, ""},
+		{"suffix lookalike", http.MethodGet, "https://evilpartner.io", http.StatusOK, ""},
+		{"scheme mismatch", http.MethodGet, "http://a.partner.io", http.StatusOK, ""},
+		{"preflight allowed", http.MethodOptions, "https://app.example.com", http.StatusNoContent, "https://app.example.com"},
+		{"preflight denied", http.MethodOptions, "https://evil.com", http.StatusForbidden, ""},
+	} {
+		req := httptest.NewRequest(tt.method, "/x", nil)
+		req.Header.Set("Origin", tt.origin)
+		if tt.method == http.MethodOptions {
+			req.Header.Set("Access-Control-Request-Method", http.MethodPost)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		if w.Code != tt.wantStatus {
+			t.Errorf("%s: status = %d, want %d", tt.name, w.Code, tt.wantStatus)
+		}
+		if got := w.Header().Get("Access-Control-Allow-Origin"); got != tt.wantAllow {
+			t.Errorf("%s: Access-Control-Allow-Origin = %q, want %q", tt.name, got, tt.wantAllow)
+		}
+		if tt.wantStatus == http.StatusNoContent && w.Header().Get("Access-Control-Max-Age") != "600" {
+			t.Errorf("%s: Access-Control-Max-Age = %q", tt.name, w.Header().Get("Access-Control-Max-Age"))
+		}
+	}
+}
+
+func TestCORSRejectsWildcardWithCredentials(t *testing.T) {
+	policy := CORSPolicy{AllowedOrigins: []string{"*"}, AllowCredentials: true}
+	if err := policy.Validate(); !errors.Is(err, ErrCORSWildcardCredentials) {
+		t.Fatalf("Validate() = %v, want %v", err, ErrCORSWildcardCredentials)
+	}
+	defer func() {
+		if recover() == nil {
+			t.Error("CORS() accepted a wildcard origin with credentials")
+		}
+	}()
+	CORS(policy)
+}
+
+func TestCSPString(t *testing.T) {
+	got := NewCSP().Directive("img-src", "'self'", "https://cdn.example.com").Directive("default-src", "'none'").String()
+	if want := "default-src 'none'; img-src 'self' https://cdn.example.com"; got != want {
+		t.Errorf("CSP = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,341 @@
+package middlewares
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	"github.com/Faze-Technologies/go-utils/logs"
+	"github.com/Faze-Technologies/go-utils/redact"
+	"github.com/Faze-Technologies/go-utils/requestid"
+	"github.com/Faze-Technologies/go-utils/response"
+	"github.com/golang-jwt/jwt/v4"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	otelcodes "go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// The gRPC interceptors below mirror the Gin middleware: chain them in the
+// same order as GinLogger, GinRecovery and AuthenticateUser —
+//
+//	grpc.NewServer(
+//		grpc.ChainUnaryInterceptor(
+//			middlewares.UnaryLoggingInterceptor(logger),
+//			middlewares.UnaryRecoveryInterceptor(logger),
+//			m.UnaryAuthInterceptor("/grpc.health.v1.Health/Check"),
+//		),
+//		grpc.ChainStreamInterceptor(...same for streams...),
+//	)
+//
+// and GetAuthUserFromContext replaces GetAuthUser inside handlers.
+
+// metadataCarrier adapts gRPC metadata to OTel's TextMapCarrier.
+type metadataCarrier metadata.MD
+
+func (mc metadataCarrier) Get(key string) string {
+	if v := metadata.MD(mc).Get(key); len(v) > 0 {
+		return v[0]
+	}
+	return ""
+}
+
+func (mc metadataCarrier) Set(key, value string) {
+	metadata.MD(mc).Set(key, value)
+}
+
+func (mc metadataCarrier) Keys() []string {
+	keys := make([]string, 0, len(mc))
+	for k := range mc {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+// serverStream lets interceptors replace a stream's context.
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
+
+// GetAuthUserFromContext is GetAuthUser for code without a gin.Context, such
+// as gRPC handlers.
+func GetAuthUserFromContext(ctx context.Context) (*UserDetails, *response.ServiceError) {
+	user, ok := ctx.Value(userContextKey).(UserDetails)
+	if !ok {
+		return nil, response.Unauthenticated("User is not authenticated")
+	}
+	return &user, nil
+}
+
+func grpcClientIP(ctx context.Context, md metadata.MD) string {
+	if xff := md.Get("x-forwarded-for"); len(xff) > 0 && xff[0] != "" {
+		return strings.TrimSpace(strings.Split(xff[0], ",")[0])
+	}
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		if host, _, err := net.SplitHostPort(p.Addr.String()); err == nil {
+			return host
+		}
+		return p.Addr.String()
+	}
+	return ""
+}
+
+// authenticateGRPC resolves the caller from the authorization metadata.
+// Both "Bearer <token>" and a bare token are accepted. The returned finish
+// func must be called with the handler's error once the call completes; it
+// audits impersonated calls.
+func (m *Middlewares) authenticateGRPC(ctx context.Context, fullMethod string) (context.Context, func(error), error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	var accessToken string
+	if v := md.Get("authorization"); len(v) > 0 {
+		accessToken = strings.TrimSpace(v[0])
+		if len(accessToken) > 7 && strings.EqualFold(accessToken[:7], "bearer ") {
+			accessToken = strings.TrimSpace(accessToken[7:])
+		}
+	}
+	if accessToken == "" {
+		return ctx, nil, response.Unauthenticated("Missing access token").ToGRPCError()
+	}
+
+	user, claims, err := m.authenticateToken(ctx, accessToken, grpcClientIP(ctx, md))
+	if err != nil {
+		return ctx, nil, response.Unauthenticated("Invalid Access Token").ToGRPCError()
+	}
+	if call, ok := ctx.Value(grpcCallInfoKey{}).(*grpcCallInfo); ok {
+		call.userId = user.Id
+	}
+	if target := md.Get(impersonationMetadata); len(target) > 0 && target[0] != "" {
+		return m.impersonateGRPC(ctx, md, fullMethod, user, claims, accessToken, target[0])
+	}
+	return withAuthenticatedUser(ctx, user, claims, accessToken), func(error) {}, nil
+}
+
+// errHandlerPanicked is what an auth interceptor reports to finish when the
+// handler panics.
+var errHandlerPanicked = status.Error(codes.Internal, "Internal Server Error")
+
+// impersonateGRPC is the gRPC counterpart of impersonate, for calls carrying
+// impersonationMetadata, as forwarded by the client interceptors from an
+// impersonated request. gRPC has no safe methods, so only the full method
+// names listed in auth.impersonation.grpc_methods may be impersonated.
+func (m *Middlewares) impersonateGRPC(ctx context.Context, md metadata.MD, fullMethod string, actor UserDetails, claims jwt.MapClaims, accessToken string, targetId string) (context.Context, func(error), error) {
+	cfg := loadImpersonationConfig()
+	event := ImpersonationAuditEvent{
+		ActorId:      actor.Id,
+		ActorEmail:   actor.Email,
+		TargetUserId: targetId,
+		Method:       "GRPC",
+		Route:        fullMethod,
+		Path:         fullMethod,
+		Ip:           grpcClientIP(ctx, md),
+		At:           time.Now(),
+	}
+
+	target, sErr, reason := m.impersonationTarget(ctx, cfg, actor, targetId, containsString(cfg.grpcMethods, fullMethod))
+	if sErr != nil {
+		grpcErr := sErr.ToGRPCError()
+		event.Outcome = ImpersonationDenied
+		event.Reason = reason
+		event.Status = int(status.Code(grpcErr))
+		m.auditImpersonation(ctx, cfg, event)
+		return ctx, nil, grpcErr
+	}
+
+	ctx = withImpersonation(ctx, actor, target, claims, accessToken)
+	finish := func(err error) {
+		event.Outcome = ImpersonationAllowed
+		event.Status = int(status.Code(err))
+		m.auditImpersonation(ctx, cfg, event)
+	}
+	return ctx, finish, nil
+}
+
+func skipMethod(fullMethod string, skip []string) bool {
+	for _, s := range skip {
+		if s == fullMethod {
+			return true
+		}
+	}
+	return false
+}
+
+// UnaryAuthInterceptor is AuthenticateUser for unary gRPC calls. Methods in
+// skipMethods (full names, e.g. "/pkg.Service/Method") are not authenticated.
+func (m *Middlewares) UnaryAuthInterceptor(skipMethods ...string) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
+		if skipMethod(info.FullMethod, skipMethods) {
+			return handler(ctx, req)
+		}
+		authCtx, finish, err := m.authenticateGRPC(ctx, info.FullMethod)
+		if err != nil {
+			return nil, err
+		}
+		// err is replaced by the handler's result unless it panics, which
+		// the recovery interceptor turns into codes.Internal.
+		err = errHandlerPanicked
+		defer func() { finish(err) }()
+		return handler(authCtx, req)
+	}
+}
+
+// StreamAuthInterceptor is AuthenticateUser for streaming gRPC calls.
+func (m *Middlewares) StreamAuthInterceptor(skipMethods ...string) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+		if skipMethod(info.FullMethod, skipMethods) {
+			return handler(srv, ss)
+		}
+		authCtx, finish, err := m.authenticateGRPC(ss.Context(), info.FullMethod)
+		if err != nil {
+			return err
+		}
+		err = errHandlerPanicked
+		defer func() { finish(err) }()
+		return handler(srv, &serverStream{ServerStream: ss, ctx: authCtx})
+	}
+}
+
+// startServerSpan continues the caller's trace and request id from incoming
+// metadata.
+func startServerSpan(ctx context.Context, fullMethod string) (context.Context, trace.Span) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	propagatedCtx := otel.GetTextMapPropagator().Extract(ctx, metadataCarrier(md))
+
+	id := metadataCarrier(md).Get(requestid.Header)
+	if !requestid.Valid(id) {
+		id = requestid.New()
+	}
+	propagatedCtx = requestid.NewContext(propagatedCtx, id)
+
+	return otel.Tracer("grpc").Start(propagatedCtx, fullMethod,
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(
+			attribute.String("rpc.system", "grpc"),
+			attribute.String("rpc.method", fullMethod),
+			attribute.String("request.id", id),
+		),
+	)
+}
+
+// grpcCallInfo is filled in by inner interceptors so the logging interceptor,
+// which runs outermost, can report who made the call.
+type grpcCallInfo struct {
+	userId string
+}
+
+type grpcCallInfoKey struct{}
+
+func logGRPCCall(ctx context.Context, logger *zap.Logger, fullMethod string, call *grpcCallInfo, start time.Time, err error) {
+	code := status.Code(err)
+	span := trace.SpanFromContext(ctx)
+	span.SetAttributes(attribute.String("rpc.grpc.status_code", code.String()))
+
+	fields := []zap.Field{
+		zap.String("method", fullMethod),
+		zap.String("code", code.String()),
+		zap.Duration("cost", time.Since(start)),
+	}
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		fields = append(fields, zap.String("ip", grpcClientIP(ctx, md)))
+	}
+	if call.userId != "" {
+		fields = append(fields, zap.String("userId", call.userId))
+		span.SetAttributes(attribute.String("user.id", call.userId))
+	}
+
+	logger = logs.WithContextLogger(ctx, logger)
+	switch code {
+	case codes.OK:
+		logger.Info(fullMethod, fields...)
+	case codes.Internal, codes.Unknown, codes.DataLoss, codes.Unavailable:
+		span.SetStatus(otelcodes.Error, code.String())
+		logger.Error(fullMethod, append(fields, zap.Error(err))...)
+	default:
+		logger.Warn(fullMethod, append(fields, zap.Error(err))...)
+	}
+}
+
+// UnaryLoggingInterceptor is GinLogger for unary gRPC calls: it starts a
+// server span continuing the caller's trace and writes one structured log
+// line per call to logger, with the trace and request id fields that
+// logs.WithContext adds.
+func UnaryLoggingInterceptor(logger *zap.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		start := time.Now()
+		spanCtx, span := startServerSpan(ctx, info.FullMethod)
+		defer span.End()
+
+		call := &grpcCallInfo{}
+		resp, err := handler(context.WithValue(spanCtx, grpcCallInfoKey{}, call), req)
+		logGRPCCall(spanCtx, logger, info.FullMethod, call, start, err)
+		return resp, err
+	}
+}
+
+// StreamLoggingInterceptor is GinLogger for streaming gRPC calls.
+func StreamLoggingInterceptor(logger *zap.Logger) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		start := time.Now()
+		spanCtx, span := startServerSpan(ss.Context(), info.FullMethod)
+		defer span.End()
+
+		call := &grpcCallInfo{}
+		err := handler(srv, &serverStream{ServerStream: ss, ctx: context.WithValue(spanCtx, grpcCallInfoKey{}, call)})
+		logGRPCCall(spanCtx, logger, info.FullMethod, call, start, err)
+		return err
+	}
+}
+
+// recoverToError logs a recovered panic and converts it to codes.Internal.
+// The panic value is redacted as for panic alerts, since it often embeds
+// the request being processed.
+func recoverToError(ctx context.Context, logger *zap.Logger, redactor *redact.Redactor, fullMethod string, p interface{}) error {
+	panicValue := redactor.Partial(fmt.Sprint(p))
+	logs.WithContextLogger(ctx, logger).Error("gRPC handler panicked",
+		zap.String("method", fullMethod),
+		zap.String("error", panicValue),
+		zap.String("stack", string(debug.Stack())),
+	)
+	trace.SpanFromContext(ctx).RecordError(fmt.Errorf("panic: %s", panicValue))
+	return status.Error(codes.Internal, "Internal Server Error")
+}
+
+// UnaryRecoveryInterceptor is GinRecovery for unary gRPC calls: a panic in
+// the handler is logged with its stack and returned as codes.Internal.
+func UnaryRecoveryInterceptor(logger *zap.Logger) grpc.UnaryServerInterceptor {
+	redactor := newServiceRedactor(logger)
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
+		defer func() {
+			if p := recover(); p != nil {
+				err = recoverToError(ctx, logger, redactor, info.FullMethod, p)
+			}
+		}()
+		return handler(ctx, req)
+	}
+}
+
+// StreamRecoveryInterceptor is GinRecovery for streaming gRPC calls.
+func StreamRecoveryInterceptor(logger *zap.Logger) grpc.StreamServerInterceptor {
+	redactor := newServiceRedactor(logger)
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+		defer func() {
+			if p := recover(); p != nil {
+				err = recoverToError(ss.Context(), logger, redactor, info.FullMethod, p)
+			}
+		}()
+		return handler(srv, ss)
+	}
+}
@@ -0,0 +1,72 @@
+package middlewares
+
+import (
+	"context"
+
+	"github.com/Faze-Technologies/go-utils/requestid"
+	"go.opentelemetry.io/otel"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// impersonationMetadata is ImpersonationHeader as gRPC metadata.
+const impersonationMetadata = "x-act-as-user"
+
+// outgoingContext forwards the caller's access token, user id and request
+// id, and the current trace context, on an outbound gRPC call. Metadata the
+// caller set explicitly is left untouched.
+//
+// The forwarded identity is always the one the access token proves. On an
+// impersonated request that is the support agent, so user-id carries the
+// agent's id, never the target's, and the target goes in x-act-as-user. The
+// downstream auth interceptors apply the same checks to it as AuthenticateUser
+// does to ImpersonationHeader.
+func outgoingContext(ctx context.Context) context.Context {
+	md, ok := metadata.FromOutgoingContext(ctx)
+	if ok {
+		md = md.Copy()
+	} else {
+		md = metadata.MD{}
+	}
+
+	if len(md.Get("authorization")) == 0 {
+		if token := AccessTokenFromContext(ctx); token != "" {
+			md.Set("authorization", "Bearer "+token)
+		}
+	}
+	user, ok := ctx.Value(userContextKey).(UserDetails)
+	if actor, impersonated := ctx.Value(actorContextKey).(UserDetails); impersonated {
+		if len(md.Get(impersonationMetadata)) == 0 && ok {
+			md.Set(impersonationMetadata, user.Id)
+		}
+		user, ok = actor, true
+	}
+	if len(md.Get("user-id")) == 0 && ok && user.Id != "" {
+		md.Set("user-id", user.Id)
+	}
+	if len(md.Get(requestid.Header)) == 0 {
+		if id := requestid.FromContext(ctx); id != "" {
+			md.Set(requestid.Header, id)
+		}
+	}
+	otel.GetTextMapPropagator().Inject(ctx, metadataCarrier(md))
+	return metadata.NewOutgoingContext(ctx, md)
+}
+
+// UnaryClientInterceptor propagates auth and trace context on outbound unary
+// calls made from within an authenticated request:
+//
+//	grpc.NewClient(addr, grpc.WithChainUnaryInterceptor(middlewares.UnaryClientInterceptor()))
+func UnaryClientInterceptor() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		return invoker(outgoingContext(ctx), method, req, reply, cc, opts...)
+	}
+}
+
+// StreamClientInterceptor propagates auth and trace context on outbound
+// streaming calls.
+func StreamClientInterceptor() grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		return streamer(outgoingContext(ctx), desc, cc, method, opts...)
+	}
+}
@@ -0,0 +1,123 @@
+package middlewares
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/Faze-Technologies/go-utils/logs"
+	"github.com/Faze-Technologies/go-utils/requestid"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestOutgoingContext(t *testing.T) {
+	ctx := withAuthenticatedUser(context.Background(), UserDetails{Id: "u1"}, nil, "tok")
+	ctx = requestid.NewContext(ctx, "req-1")
+
+	md, _ := metadata.FromOutgoingContext(outgoingContext(ctx))
+	for key, want := range map[string]string{"authorization": "Bearer tok", "user-id": "u1", requestid.Header: "req-1"} {
+		if got := md.Get(key); len(got) != 1 || got[0] != want {
+			t.Errorf("%s = %v, want %q", key, got, want)
+		}
+	}
+	if got := md.Get(impersonationMetadata); len(got) != 0 {
+		t.Errorf("%s = %v on a plain request", impersonationMetadata, got)
+	}
+
+	// Explicit metadata wins.
+	explicit := metadata.AppendToOutgoingContext(ctx, "user-id", "service")
+	md, _ = metadata.FromOutgoingContext(outgoingContext(explicit))
+	if got := md.Get("user-id"); len(got) != 1 || got[0] != "service" {
+		t.Errorf("explicit user-id = %v, want [service]", got)
+	}
+}
+
+func TestOutgoingContextImpersonated(t *testing.T) {
+	ctx := withAuthenticatedUser(context.Background(), UserDetails{Id: "target"}, nil, "agent-token")
+	ctx = context.WithValue(ctx, actorContextKey, UserDetails{Id: "agent"})
+
+	md, _ := metadata.FromOutgoingContext(outgoingContext(ctx))
+	if got := md.Get("user-id"); len(got) != 1 || got[0] != "agent" {
+		t.Errorf("user-id = %v, want the agent the token belongs to", got)
+	}
+	if got := md.Get(impersonationMetadata); len(got) != 1 || got[0] != "target" {
+		t.Errorf("%s = %v, want [target]", impersonationMetadata, got)
+	}
+}
+
+func TestUnaryAuthInterceptor(t *testing.T) {
+	logs.NewLogger()
+	m := &Middlewares{Logger: zap.NewNop()}
+	interceptor := m.UnaryAuthInterceptor("/grpc.health.v1.Health/Check")
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		if _, sErr := GetAuthUserFromContext(ctx); sErr != nil {
+			return "anonymous", nil
+		}
+		return "user", nil
+	}
+
+	resp, err := interceptor(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: "/grpc.health.v1.Health/Check"}, handler)
+	if err != nil || resp != "anonymous" {
+		t.Errorf("skipped method: got %v, %v", resp, err)
+	}
+
+	for _, auth := range []string{"", "Bearer not-a-jwt"} {
+		ctx := context.Background()
+		if auth != "" {
+			ctx = metadata.NewIncomingContext(ctx, metadata.Pairs("authorization", auth))
+		}
+		_, err := interceptor(ctx, nil, &grpc.UnaryServerInfo{FullMethod: "/pkg.Wallet/Debit"}, handler)
+		if status.Code(err) != codes.Unauthenticated {
+			t.Errorf("authorization %q: got %v, want Unauthenticated", auth, err)
+		}
+	}
+}
+
+func TestUnaryLoggingAndRecoveryInterceptors(t *testing.T) {
+	logs.NewLogger()
+	logging := UnaryLoggingInterceptor(zap.NewNop())
+	recovery := UnaryRecoveryInterceptor(zap.NewNop())
+	info := &grpc.UnaryServerInfo{FullMethod: "/pkg.Wallet/Debit"}
+
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(requestid.Header, "req-42"))
+	var seen string
+	_, err := logging(ctx, nil, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return recovery(ctx, req, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+			seen = requestid.FromContext(ctx)
+			panic("boom")
+		})
+	})
+	if status.Code(err) != codes.Internal {
+		t.Errorf("panicking handler: got %v, want Internal", err)
+	}
+	if seen != "req-42" {
+		t.Errorf("request id in handler = %q, want req-42", seen)
+	}
+}
+
+func TestRecoveryInterceptorRedactsPanicValue(t *testing.T) {
+	logs.NewLogger()
+	core, recorded := observer.New(zap.ErrorLevel)
+	recovery := UnaryRecoveryInterceptor(zap.New(core))
+
+	_, err := recovery(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: "/pkg.Auth/Login"},
+		func(ctx context.Context, req interface{}) (interface{}, error) {
+			panic(`decode {"password":"hunter2"}: unexpected EOF`)
+		})
+	if status.Code(err) != codes.Internal {
+		t.Fatalf("got %v, want Internal", err)
+	}
+	entries := recorded.All()
+	if len(entries) != 1 {
+		t.Fatalf("logged %d entries, want 1", len(entries))
+	}
+	logged := entries[0].ContextMap()["error"].(string)
+	if strings.Contains(logged, "hunter2") || !strings.Contains(logged, "unexpected EOF") {
+		t.Errorf("logged panic value = %q, want it redacted", logged)
+	}
+}
@@ -0,0 +1,242 @@
+package middlewares
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/Faze-Technologies/go-utils/config"
+	"github.com/Faze-Technologies/go-utils/logs"
+	"github.com/Faze-Technologies/go-utils/response"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v4"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+// ImpersonationHeader names the user a support agent wants to act as. It is
+// only honoured on tokens whose user holds the impersonation scope.
+const ImpersonationHeader = "X-Act-As-User"
+
+// Impersonation defaults, overridable via auth.impersonation.scope and
+// auth.impersonation.audit_topic.
+const (
+	defaultImpersonationScope      = "admin:impersonate"
+	defaultImpersonationAuditTopic = "auth-impersonation-audit"
+)
+
+const actorContextKey contextKey = "impersonator"
+
+// UserLoader loads a user's current details by id. It backs impersonation,
+// where the target's details do not come from the token.
+type UserLoader interface {
+	LoadUser(ctx context.Context, userId string) (UserDetails, error)
+}
+
+// AuditPublisher publishes audit events. *pubsub.PubSub satisfies it.
+type AuditPublisher interface {
+	Publish(ctx context.Context, topicID string, payload interface{}, attrs map[string]string) (string, error)
+}
+
+// Impersonation audit outcomes.
+const (
+	ImpersonationAllowed = "allowed"
+	ImpersonationDenied  = "denied"
+)
+
+// ImpersonationAuditEvent is logged and published for every request carrying
+// ImpersonationHeader, whether or not it was let through. For gRPC calls
+// Method is "GRPC", Route and Path hold the full method name and Status the
+// gRPC code.
+type ImpersonationAuditEvent struct {
+	ActorId      string    `json:"actorId"`
+	ActorEmail   string    `json:"actorEmail"`
+	TargetUserId string    `json:"targetUserId"`
+	Method       string    `json:"method"`
+	Route        string    `json:"route"`
+	Path         string    `json:"path"`
+	Ip           string    `json:"ip"`
+	Outcome      string    `json:"outcome"`
+	Reason       string    `json:"reason,omitempty"`
+	Status       int       `json:"status"`
+	At           time.Time `json:"at"`
+}
+
+type impersonationConfig struct {
+	scope          string
+	auditTopic     string
+	writableRoutes []string
+	grpcMethods    []string
+}
+
+func loadImpersonationConfig() impersonationConfig {
+	cfg := impersonationConfig{
+		scope:          config.GetString("auth.impersonation.scope"),
+		auditTopic:     config.GetString("auth.impersonation.audit_topic"),
+		writableRoutes: config.GetSlice("auth.impersonation.writable_routes"),
+		grpcMethods:    config.GetSlice("auth.impersonation.grpc_methods"),
+	}
+	if cfg.scope == "" {
+		cfg.scope = defaultImpersonationScope
+	}
+	if cfg.auditTopic == "" {
+		cfg.auditTopic = defaultImpersonationAuditTopic
+	}
+	return cfg
+}
+
+// impersonationWritable reports whether the request may change state while
+// impersonating. Safe methods always may; anything else only on routes listed
+// in auth.impersonation.writable_routes as "METHOD /route/:param".
+func impersonationWritable(c *gin.Context, cfg impersonationConfig) bool {
+	switch c.Request.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	}
+	return containsString(cfg.writableRoutes, c.Request.Method+" "+c.FullPath())
+}
+
+// GetImpersonator returns the support agent acting on behalf of the
+// authenticated user, if the request is impersonated. GetAuthUser returns
+// the target user in that case.
+func GetImpersonator(c *gin.Context) (*UserDetails, bool) {
+	return GetImpersonatorFromContext(c.Request.Context())
+}
+
+// GetImpersonatorFromContext is GetImpersonator for gRPC handlers.
+func GetImpersonatorFromContext(ctx context.Context) (*UserDetails, bool) {
+	actor, ok := ctx.Value(actorContextKey).(UserDetails)
+	if !ok {
+		return nil, false
+	}
+	return &actor, true
+}
+
+// IsImpersonated reports whether the request is being made by a support
+// agent acting as the authenticated user.
+func IsImpersonated(c *gin.Context) bool {
+	_, ok := GetImpersonator(c)
+	return ok
+}
+
+// impersonate is the AuthenticateUser path for requests carrying
+// ImpersonationHeader: actor has already been authenticated from the token.
+// It swaps in the target user, keeps actor in context, runs the rest of the
+// chain and audits the outcome.
+func (m *Middlewares) impersonate(c *gin.Context, actor UserDetails, claims jwt.MapClaims, accessToken string) {
+	ctx := c.Request.Context()
+	cfg := loadImpersonationConfig()
+	event := ImpersonationAuditEvent{
+		ActorId:      actor.Id,
+		ActorEmail:   actor.Email,
+		TargetUserId: c.GetHeader(ImpersonationHeader),
+		Method:       c.Request.Method,
+		Route:        c.FullPath(),
+		Path:         c.Request.URL.Path,
+		Ip:           c.ClientIP(),
+		At:           time.Now(),
+	}
+
+	deny := func(sErr *response.ServiceError, reason string) {
+		event.Outcome = ImpersonationDenied
+		event.Reason = reason
+		event.Status = sErr.ToHTTPStatus()
+		m.auditImpersonation(ctx, cfg, event)
+		response.SendHTTPError(c, sErr)
+		c.Abort()
+	}
+
+	target, sErr, reason := m.impersonationTarget(ctx, cfg, actor, event.TargetUserId, impersonationWritable(c, cfg))
+	if sErr != nil {
+		deny(sErr, reason)
+		return
+	}
+
+	ctx = withImpersonation(ctx, actor, target, claims, accessToken)
+	c.Request = c.Request.WithContext(ctx)
+
+	// Audited in a defer so a panicking handler still leaves a trail.
+	event.Outcome = ImpersonationAllowed
+	completed := false
+	defer func() {
+		event.Status = c.Writer.Status()
+		if !completed {
+			// GinRecovery only writes the 500 after this runs.
+			event.Status = http.StatusInternalServerError
+		}
+		m.auditImpersonation(ctx, cfg, event)
+	}()
+	c.Next()
+	completed = true
+}
+
+// impersonationTarget applies the checks shared by HTTP and gRPC
+// impersonation and loads the target. writable reports whether the request
+// may change state. On refusal it returns the error to send and the audit
+// reason.
+func (m *Middlewares) impersonationTarget(ctx context.Context, cfg impersonationConfig, actor UserDetails, targetId string, writable bool) (UserDetails, *response.ServiceError, string) {
+	if !HasScope(cfg.scope).Evaluate(&actor) {
+		return UserDetails{}, response.PermissionDenied("Impersonation is not allowed"), "missing_scope"
+	}
+	if targetId == actor.Id {
+		return UserDetails{}, response.InvalidArgument("Cannot impersonate yourself"), "self"
+	}
+	if m.Users == nil {
+		return UserDetails{}, response.PermissionDenied("Impersonation is not enabled"), "not_enabled"
+	}
+	if !writable {
+		return UserDetails{}, response.PermissionDenied("Impersonated requests are read-only"), "read_only"
+	}
+
+	target, err := m.Users.LoadUser(ctx, targetId)
+	if err != nil {
+		logs.WithContext(ctx).Error("Error loading impersonation target",
+			zap.String("actorId", actor.Id),
+			zap.String("targetUserId", targetId),
+			zap.Error(err),
+		)
+		return UserDetails{}, response.NotFound("Target user not found"), "target_not_found"
+	}
+	return target, nil, ""
+}
+
+// withImpersonation stores target as the authenticated user and actor as
+// the impersonator, and marks the span.
+func withImpersonation(ctx context.Context, actor, target UserDetails, claims jwt.MapClaims, accessToken string) context.Context {
+	trace.SpanFromContext(ctx).SetAttributes(
+		attribute.Bool("auth.impersonated", true),
+		attribute.String("auth.actor_id", actor.Id),
+	)
+	ctx = withAuthenticatedUser(ctx, target, claims, accessToken)
+	return context.WithValue(ctx, actorContextKey, actor)
+}
+
+// auditImpersonation logs the event and publishes it in the background so
+// the audit trail never delays or fails the response.
+func (m *Middlewares) auditImpersonation(ctx context.Context, cfg impersonationConfig, event ImpersonationAuditEvent) {
+	logs.WithContext(ctx).Info("Impersonated request",
+		zap.String("actorId", event.ActorId),
+		zap.String("targetUserId", event.TargetUserId),
+		zap.String("method", event.Method),
+		zap.String("route", event.Route),
+		zap.String("outcome", event.Outcome),
+		zap.String("reason", event.Reason),
+		zap.Int("status", event.Status),
+	)
+	if m.Audit == nil {
+		return
+	}
+
+	ctx = context.WithoutCancel(ctx)
+	go func() {
+		attrs := map[string]string{"actorId": event.ActorId, "outcome": event.Outcome}
+		if _, err := m.Audit.Publish(ctx, cfg.auditTopic, event, attrs); err != nil {
+			logs.WithContext(ctx).Error("Error publishing impersonation audit event",
+				zap.String("actorId", event.ActorId),
+				zap.String("targetUserId", event.TargetUserId),
+				zap.Error(err),
+			)
+		}
+	}()
+}
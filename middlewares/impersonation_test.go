@@ -0,0 +1,177 @@
+package middlewares_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Faze-Technologies/go-utils/authtest"
+	"github.com/Faze-Technologies/go-utils/config"
+	"github.com/Faze-Technologies/go-utils/middlewares"
+	"github.com/gin-gonic/gin"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+type usersStub map[string]middlewares.UserDetails
+
+func (u usersStub) LoadUser(_ context.Context, userId string) (middlewares.UserDetails, error) {
+	user, ok := u[userId]
+	if !ok {
+		return middlewares.UserDetails{}, errors.New("not found")
+	}
+	return user, nil
+}
+
+func TestImpersonation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	kit := authtest.New(t)
+	m := kit.Middlewares()
+	m.Users = usersStub{"target": {Id: "target"}}
+
+	var got, actor *middlewares.UserDetails
+	handler := func(c *gin.Context) {
+		got, _ = middlewares.GetAuthUser(c)
+		actor, _ = middlewares.GetImpersonator(c)
+		c.Status(http.StatusOK)
+	}
+	r := gin.New()
+	r.GET("/me", m.AuthenticateUser, handler)
+	r.POST("/me", m.AuthenticateUser, handler)
+
+	admin := kit.Token(middlewares.UserDetails{Id: "agent", Metadata: map[string]interface{}{"scopes": []string{"admin:impersonate"}}})
+	plain := kit.Token(middlewares.UserDetails{Id: "agent"})
+
+	for _, tt := range []struct {
+		name       string
+		method     string
+		token      string
+		target     string
+		wantStatus int
+	}{
+		{"read as target", http.MethodGet, admin, "target", http.StatusOK},
+		{"missing scope", http.MethodGet, plain, "target", http.StatusForbidden},
+		{"read-only", http.MethodPost, admin, "target", http.StatusForbidden},
+		{"unknown target", http.MethodGet, admin, "ghost", http.StatusNotFound},
+	} {
+		got, actor = nil, nil
+		req := httptest.NewRequest(tt.method, "/me", nil)
+		req.Header.Set("Authorization", tt.token)
+		req.Header.Set(middlewares.ImpersonationHeader, tt.target)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		if w.Code != tt.wantStatus {
+			t.Errorf("%s: status = %d, want %d", tt.name, w.Code, tt.wantStatus)
+			continue
+		}
+		if tt.wantStatus == http.StatusOK && (got == nil || got.Id != "target" || actor == nil || actor.Id != "agent") {
+			t.Errorf("%s: user = %+v, actor = %+v", tt.name, got, actor)
+		}
+	}
+}
+
+func TestImpersonationAuditedOnPanic(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	kit := authtest.New(t)
+	m := kit.Middlewares()
+	m.Users = usersStub{"target": {Id: "target"}}
+	audit := make(auditStub, 1)
+	m.Audit = audit
+
+	r := gin.New()
+	r.Use(gin.CustomRecovery(func(c *gin.Context, _ interface{}) { c.AbortWithStatus(http.StatusInternalServerError) }))
+	r.GET("/me", m.AuthenticateUser, func(c *gin.Context) { panic("boom") })
+
+	req := httptest.NewRequest(http.MethodGet, "/me", nil)
+	req.Header.Set("Authorization", kit.Token(middlewares.UserDetails{Id: "agent", Metadata: map[string]interface{}{"scopes": []string{"admin:impersonate"}}}))
+	req.Header.Set(middlewares.ImpersonationHeader, "target")
+	r.ServeHTTP(httptest.NewRecorder(), req)
+
+	event := audit.next(t)
+	if event.Outcome != middlewares.ImpersonationAllowed || event.Status != http.StatusInternalServerError {
+		t.Errorf("audit event = %+v, want an allowed event with status 500", event)
+	}
+}
+
+// auditStub collects published audit events.
+type auditStub chan middlewares.ImpersonationAuditEvent
+
+func (a auditStub) Publish(_ context.Context, _ string, payload interface{}, _ map[string]string) (string, error) {
+	a <- payload.(middlewares.ImpersonationAuditEvent)
+	return "", nil
+}
+
+// next waits for the event published in the background.
+func (a auditStub) next(t *testing.T) middlewares.ImpersonationAuditEvent {
+	t.Helper()
+	select {
+	case event := <-a:
+		return event
+	case <-time.After(5 * time.Second):
+		t.Fatal("no audit event published")
+		return middlewares.ImpersonationAuditEvent{}
+	}
+}
+
+func TestGRPCImpersonation(t *testing.T) {
+	kit := authtest.New(t)
+	m := kit.Middlewares()
+	m.Users = usersStub{"target": {Id: "target"}}
+	audit := make(auditStub, 1)
+	m.Audit = audit
+
+	prev := config.Get("auth.impersonation.grpc_methods")
+	t.Cleanup(func() { config.Set("auth.impersonation.grpc_methods", prev) })
+	config.Set("auth.impersonation.grpc_methods", []string{"/pkg.Wallet/Balance"})
+
+	admin := kit.Token(middlewares.UserDetails{Id: "agent", Metadata: map[string]interface{}{"scopes": []string{"admin:impersonate"}}})
+	plain := kit.Token(middlewares.UserDetails{Id: "agent"})
+
+	interceptor := m.UnaryAuthInterceptor()
+	var got, actor *middlewares.UserDetails
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		got, _ = middlewares.GetAuthUserFromContext(ctx)
+		actor, _ = middlewares.GetImpersonatorFromContext(ctx)
+		return nil, nil
+	}
+
+	for _, tt := range []struct {
+		name     string
+		token    string
+		method   string
+		wantCode codes.Code
+	}{
+		{"listed method", admin, "/pkg.Wallet/Balance", codes.OK},
+		{"unlisted method", admin, "/pkg.Wallet/Debit", codes.PermissionDenied},
+		{"missing scope", plain, "/pkg.Wallet/Balance", codes.PermissionDenied},
+	} {
+		got, actor = nil, nil
+		ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(
+			"authorization", "Bearer "+tt.token,
+			"x-act-as-user", "target",
+		))
+		_, err := interceptor(ctx, nil, &grpc.UnaryServerInfo{FullMethod: tt.method}, handler)
+		if status.Code(err) != tt.wantCode {
+			t.Errorf("%s: code = %v, want %v", tt.name, status.Code(err), tt.wantCode)
+			continue
+		}
+
+		event := audit.next(t)
+		wantOutcome := middlewares.ImpersonationDenied
+		if tt.wantCode == codes.OK {
+			wantOutcome = middlewares.ImpersonationAllowed
+			if got == nil || got.Id != "target" || actor == nil || actor.Id != "agent" {
+				t.Errorf("%s: user = %+v, actor = %+v", tt.name, got, actor)
+			}
+		}
+		if event.Outcome != wantOutcome || event.Route != tt.method || event.Status != int(tt.wantCode) {
+			t.Errorf("%s: audit event = %+v", tt.name, event)
+		}
+	}
+}
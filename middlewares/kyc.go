@@ -0,0 +1,141 @@
+package middlewares
+
+import (
+	"strings"
+
+	"github.com/Faze-Technologies/go-utils/config"
+	"github.com/Faze-Technologies/go-utils/geoip"
+	"github.com/Faze-Technologies/go-utils/logs"
+	"github.com/Faze-Technologies/go-utils/response"
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+// KYCReason is the machine-readable reason returned in the "reason" detail of
+// a VERIFICATION_REQUIRED error so apps can route the user to the right screen.
+type KYCReason string
+
+const (
+	KYCReasonNotVerified       KYCReason = "kyc_not_verified"
+	KYCReasonCountryMissing    KYCReason = "kyc_country_missing"
+	KYCReasonCountryNotAllowed KYCReason = "kyc_country_not_allowed"
+	KYCReasonCountryDenied     KYCReason = "kyc_country_denied"
+	KYCReasonGeoMismatch       KYCReason = "kyc_geo_country_mismatch"
+)
+
+// kycGeoMismatchKey is set on the gin context when the KYC country differs
+// from the geoip-resolved country, so handlers can apply extra risk checks.
+const kycGeoMismatchKey = "kycGeoMismatch"
+
+// KYCPolicy describes what RequireKYC enforces. Country lists hold ISO-3166
+// alpha-2 codes and are compared case-insensitively. An empty allow list
+// allows every country that is not explicitly denied.
+type KYCPolicy struct {
+	AllowedCountries []string
+	DeniedCountries  []string
+	// CheckGeoMismatch compares KycCountry with the country resolved by the
+	// geoip middleware. Mismatches are logged and recorded on the span; set
+	// RejectGeoMismatch to also block the request.
+	CheckGeoMismatch  bool
+	RejectGeoMismatch bool
+}
+
+// LoadKYCPolicy reads a KYCPolicy from config under prefix, e.g. "kyc.withdraw":
+//
+//	"kyc": {"withdraw": {"allowed_countries": ["IN"], "denied_countries": [],
+//	        "check_geo_mismatch": true, "reject_geo_mismatch": false}}
+func LoadKYCPolicy(prefix string) KYCPolicy {
+	return KYCPolicy{
+		AllowedCountries:  config.GetSlice(prefix + ".allowed_countries"),
+		DeniedCountries:   config.GetSlice(prefix + ".denied_countries"),
+		CheckGeoMismatch:  config.GetBool(prefix + ".check_geo_mismatch"),
+		RejectGeoMismatch: config.GetBool(prefix + ".reject_geo_mismatch"),
+	}
+}
+
+func containsCountry(countries []string, country string) bool {
+	for _, c := range countries {
+		if strings.EqualFold(c, country) {
+			return true
+		}
+	}
+	return false
+}
+
+// evaluate returns the reason the user fails the policy, or "" if they pass.
+// Geo mismatch is handled separately since it is not always blocking.
+func (p KYCPolicy) evaluate(user *UserDetails) KYCReason {
+	if !user.KycStatus {
+		return KYCReasonNotVerified
+	}
+	if user.KycCountry == "" {
+		if len(p.AllowedCountries) > 0 {
+			return KYCReasonCountryMissing
+		}
+		return ""
+	}
+	if containsCountry(p.DeniedCountries, user.KycCountry) {
+		return KYCReasonCountryDenied
+	}
+	if len(p.AllowedCountries) > 0 && !containsCountry(p.AllowedCountries, user.KycCountry) {
+		return KYCReasonCountryNotAllowed
+	}
+	return ""
+}
+
+// IsKYCGeoMismatch reports whether RequireKYC flagged the current request
+// for a KYC country / geoip country mismatch.
+func IsKYCGeoMismatch(c *gin.Context) bool {
+	return c.GetBool(kycGeoMismatchKey)
+}
+
+// RequireKYC returns a middleware that only lets KYC-verified users whose KYC
+// country satisfies policy through. It must run after AuthenticateUser; the
+// geo mismatch check additionally needs the geoip middleware.
+func RequireKYC(policy KYCPolicy) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+		span := trace.SpanFromContext(ctx)
+
+		user, sErr := GetAuthUser(c)
+		if sErr != nil {
+			response.SendHTTPError(c, response.Unauthenticated("User is not authenticated"))
+			c.Abort()
+			return
+		}
+
+		reason := policy.evaluate(user)
+
+		if reason == "" && policy.CheckGeoMismatch {
+			if geo := geoip.FromContext(ctx); geo != nil && geo.Country != "" && user.KycCountry != "" &&
+				!strings.EqualFold(geo.Country, user.KycCountry) {
+				c.Set(kycGeoMismatchKey, true)
+				span.SetAttributes(attribute.Bool("kyc.geo_mismatch", true))
+				logs.WithContext(ctx).Warn("KYC country does not match geo country",
+					zap.String("userId", user.Id),
+					zap.String("kycCountry", user.KycCountry),
+					zap.String("geoCountry", geo.Country),
+				)
+				if policy.RejectGeoMismatch {
+					reason = KYCReasonGeoMismatch
+				}
+			}
+		}
+
+		if reason != "" {
+			span.SetAttributes(attribute.String("kyc.denied_reason", string(reason)))
+			logs.WithContext(ctx).Info("KYC requirement not met",
+				zap.String("userId", user.Id),
+				zap.String("reason", string(reason)),
+				zap.String("kycCountry", user.KycCountry),
+			)
+			response.SendHTTPError(c, response.VerificationRequired("KYC verification required").
+				WithDetails("reason", string(reason)))
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,81 @@
+package middlewares
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Faze-Technologies/go-utils/geoip"
+	"github.com/Faze-Technologies/go-utils/logs"
+	"github.com/gin-gonic/gin"
+)
+
+func TestRequireKYC(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	logs.NewLogger()
+
+	verified := func(country string) *UserDetails {
+		return &UserDetails{Id: "u1", KycStatus: true, KycCountry: country}
+	}
+	allowIN := KYCPolicy{AllowedCountries: []string{"IN"}, DeniedCountries: []string{"PK"}}
+	cases := []struct {
+		name     string
+		policy   KYCPolicy
+		user     *UserDetails
+		geo      string
+		status   int
+		reason   KYCReason
+		mismatch bool
+	}{
+		{"anonymous", allowIN, nil, "", http.StatusUnauthorized, "", false},
+		{"not verified", allowIN, &UserDetails{Id: "u1", KycCountry: "IN"}, "", http.StatusPreconditionRequired, KYCReasonNotVerified, false},
+		{"allowed", allowIN, verified("in"), "", http.StatusOK, "", false},
+		{"country missing", allowIN, verified(""), "", http.StatusPreconditionRequired, KYCReasonCountryMissing, false},
+		{"country not allowed", allowIN, verified("US"), "", http.StatusPreconditionRequired, KYCReasonCountryNotAllowed, false},
+		{"country denied", KYCPolicy{DeniedCountries: []string{"PK"}}, verified("PK"), "", http.StatusPreconditionRequired, KYCReasonCountryDenied, false},
+		{"no lists", KYCPolicy{}, verified(""), "", http.StatusOK, "", false},
+		{"geo mismatch logged", KYCPolicy{CheckGeoMismatch: true}, verified("IN"), "AE", http.StatusOK, "", true},
+		{"geo mismatch rejected", KYCPolicy{CheckGeoMismatch: true, RejectGeoMismatch: true}, verified("IN"), "AE", http.StatusPreconditionRequired, KYCReasonGeoMismatch, false},
+		{"geo match", KYCPolicy{CheckGeoMismatch: true, RejectGeoMismatch: true}, verified("IN"), "IN", http.StatusOK, "", false},
+		{"geo unchecked", KYCPolicy{}, verified("IN"), "AE", http.StatusOK, "", false},
+	}
+	for _, tc := range cases {
+		var mismatch bool
+		r := gin.New()
+		r.GET("/withdraw", func(c *gin.Context) {
+			ctx := c.Request.Context()
+			if tc.user != nil {
+				ctx = ContextWithUser(ctx, *tc.user)
+			}
+			if tc.geo != "" {
+				ctx = geoip.NewContext(ctx, &geoip.GeoResult{Country: tc.geo})
+			}
+			c.Request = c.Request.WithContext(ctx)
+		}, RequireKYC(tc.policy), func(c *gin.Context) {
+			mismatch = IsKYCGeoMismatch(c)
+			c.Status(http.StatusOK)
+		})
+
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/withdraw", nil))
+		if w.Code != tc.status {
+			t.Errorf("%s: status = %d, want %d", tc.name, w.Code, tc.status)
+		}
+		if mismatch != tc.mismatch {
+			t.Errorf("%s: IsKYCGeoMismatch() = %v, want %v", tc.name, mismatch, tc.mismatch)
+		}
+		if tc.reason == "" {
+			continue
+		}
+		var body struct {
+			Details map[string]interface{} `json:"details"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+			t.Fatalf("%s: decoding %s: %v", tc.name, w.Body.String(), err)
+		}
+		if got := body.Details["reason"]; got != string(tc.reason) {
+			t.Errorf("%s: reason = %v, want %s (body %s)", tc.name, got, tc.reason, w.Body.String())
+		}
+	}
+}
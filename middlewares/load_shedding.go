@@ -0,0 +1,250 @@
+package middlewares
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Faze-Technologies/go-utils/config"
+	"github.com/Faze-Technologies/go-utils/logs"
+	"github.com/Faze-Technologies/go-utils/response"
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+// RoutePriority decides which share of the concurrency limit a route may
+// use, so that under load the low-priority routes are shed first.
+type RoutePriority string
+
+const (
+	// PriorityCritical routes, e.g. payments, may use the whole limit.
+	PriorityCritical RoutePriority = "critical"
+	// PriorityNormal is the default: 90% of the limit.
+	PriorityNormal RoutePriority = "normal"
+	// PriorityLow routes, e.g. feeds and recommendations, may use half.
+	PriorityLow RoutePriority = "low"
+)
+
+func (p RoutePriority) share() float64 {
+	switch p {
+	case PriorityCritical:
+		return 1
+	case PriorityLow:
+		return 0.5
+	}
+	return 0.9
+}
+
+// Load shedding defaults applied by LoadConcurrencyPolicy.
+const (
+	defaultInitialLimit     = 100
+	defaultMinLimit         = 10
+	defaultMaxLimit         = 1000
+	defaultLatencyThreshold = 500 * time.Millisecond
+	defaultBackoffRatio     = 0.9
+	defaultRetryAfter       = time.Second
+)
+
+// defaultExemptRoutes are the health package's probe routes. Shedding a
+// probe would get an overloaded pod restarted or pulled from the load
+// balancer for the wrong reason.
+var defaultExemptRoutes = []string{"/healthz/live", "/healthz/ready"}
+
+// ConcurrencyPolicy configures AdaptiveConcurrency. The limit follows AIMD:
+// every request that completes within LatencyThreshold while the limit is
+// at least half used raises it by one; a request slower than that, or one
+// answered with 504, multiplies it by BackoffRatio, at most once per
+// LatencyThreshold so a burst of slow responses counts as one signal. A 503
+// is not a signal: handlers return it when a dependency is down, which more
+// concurrency headroom would not fix.
+type ConcurrencyPolicy struct {
+	InitialLimit     int
+	MinLimit         int
+	MaxLimit         int
+	LatencyThreshold time.Duration
+	BackoffRatio     float64
+	RetryAfter       time.Duration
+	// Priorities maps "METHOD /route/:param" to a priority. Unlisted routes
+	// are PriorityNormal.
+	Priorities map[string]RoutePriority
+	// ExemptRoutes are never shed and do not move the limit. Nil means the
+	// health probe routes.
+	ExemptRoutes []string
+}
+
+// LoadConcurrencyPolicy reads a ConcurrencyPolicy from config under prefix,
+// e.g. "load_shedding":
+//
+//	"load_shedding": {"initial_limit": 100, "min_limit": 10, "max_limit": 1000,
+//	    "latency_threshold_ms": 500, "backoff_ratio": 0.9, "retry_after_seconds": 1,
+//	    "priorities": {"POST /v1/payments": "critical", "GET /v1/feed": "low"},
+//	    "exempt_routes": ["/healthz/live", "/healthz/ready", "/metrics"]}
+//
+// Unset keys fall back to the defaults above.
+func LoadConcurrencyPolicy(prefix string) ConcurrencyPolicy {
+	p := ConcurrencyPolicy{
+		InitialLimit:     config.GetInt(prefix + ".initial_limit"),
+		MinLimit:         config.GetInt(prefix + ".min_limit"),
+		MaxLimit:         config.GetInt(prefix + ".max_limit"),
+		LatencyThreshold: time.Duration(config.GetInt(prefix+".latency_threshold_ms")) * time.Millisecond,
+		RetryAfter:       time.Duration(config.GetInt(prefix+".retry_after_seconds")) * time.Second,
+		Priorities:       map[string]RoutePriority{},
+		ExemptRoutes:     config.GetSlice(prefix + ".exempt_routes"),
+	}
+	if len(p.ExemptRoutes) == 0 {
+		p.ExemptRoutes = nil
+	}
+	if ratio, ok := config.Get(prefix + ".backoff_ratio").(float64); ok {
+		p.BackoffRatio = ratio
+	}
+	// Viper lowercases map keys, so routes are matched case-insensitively.
+	for route, priority := range config.GetStringMap(prefix + ".priorities") {
+		p.Priorities[route] = RoutePriority(strings.ToLower(priority))
+	}
+	return p
+}
+
+func (p ConcurrencyPolicy) withDefaults() ConcurrencyPolicy {
+	if p.MinLimit <= 0 {
+		p.MinLimit = defaultMinLimit
+	}
+	if p.MaxLimit <= 0 {
+		p.MaxLimit = defaultMaxLimit
+	}
+	if p.MaxLimit < p.MinLimit {
+		p.MaxLimit = p.MinLimit
+	}
+	if p.InitialLimit <= 0 {
+		p.InitialLimit = defaultInitialLimit
+	}
+	p.InitialLimit = min(max(p.InitialLimit, p.MinLimit), p.MaxLimit)
+	if p.LatencyThreshold <= 0 {
+		p.LatencyThreshold = defaultLatencyThreshold
+	}
+	if p.BackoffRatio <= 0 || p.BackoffRatio >= 1 {
+		p.BackoffRatio = defaultBackoffRatio
+	}
+	if p.RetryAfter <= 0 {
+		p.RetryAfter = defaultRetryAfter
+	}
+	if p.ExemptRoutes == nil {
+		p.ExemptRoutes = defaultExemptRoutes
+	}
+	priorities := make(map[string]RoutePriority, len(p.Priorities))
+	for route, priority := range p.Priorities {
+		priorities[strings.ToLower(route)] = priority
+	}
+	p.Priorities = priorities
+	return p
+}
+
+func (p ConcurrencyPolicy) priority(c *gin.Context) RoutePriority {
+	if priority, ok := p.Priorities[strings.ToLower(c.Request.Method+" "+c.FullPath())]; ok {
+		return priority
+	}
+	return PriorityNormal
+}
+
+// aimdLimiter tracks the adaptive limit and the requests in flight.
+type aimdLimiter struct {
+	policy ConcurrencyPolicy
+
+	mu           sync.Mutex
+	limit        float64
+	inFlight     int
+	lastDecrease time.Time
+}
+
+func newAIMDLimiter(policy ConcurrencyPolicy) *aimdLimiter {
+	return &aimdLimiter{policy: policy, limit: float64(policy.InitialLimit)}
+}
+
+// acquire admits a request if the requests in flight fit within the share
+// of the limit its priority allows, which is never less than one.
+func (l *aimdLimiter) acquire(priority RoutePriority) (int, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	allowed := max(int(math.Floor(l.limit*priority.share())), 1)
+	if l.inFlight >= allowed {
+		return int(l.limit), false
+	}
+	l.inFlight++
+	return int(l.limit), true
+}
+
+// release records the outcome of an admitted request and adjusts the limit.
+func (l *aimdLimiter) release(latency time.Duration, overloaded bool, now time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	inFlight := l.inFlight
+	l.inFlight--
+
+	if overloaded || latency > l.policy.LatencyThreshold {
+		if now.Sub(l.lastDecrease) < l.policy.LatencyThreshold {
+			return
+		}
+		l.lastDecrease = now
+		l.limit = math.Max(l.limit*l.policy.BackoffRatio, float64(l.policy.MinLimit))
+		return
+	}
+	// Only grow while the limit is actually being used, or an idle service
+	// would drift up to MaxLimit and stop protecting itself.
+	if float64(inFlight)*2 >= l.limit {
+		l.limit = math.Min(l.limit+1, float64(l.policy.MaxLimit))
+	}
+}
+
+// AdaptiveConcurrency sheds load before the service degrades. It caps the
+// requests in flight at a limit that adapts to observed latency (see
+// ConcurrencyPolicy) and rejects requests over their priority's share of it
+// immediately with 503 UNAVAILABLE and Retry-After, rather than queueing
+// them. Register it early, after tracing and logging but before
+// authentication, so rejected requests cost as little as possible. Routes in
+// ExemptRoutes bypass it entirely:
+//
+//	r.Use(middlewares.AdaptiveConcurrency(middlewares.LoadConcurrencyPolicy("load_shedding")))
+//
+// The limit is per process, so each pod protects itself.
+func AdaptiveConcurrency(policy ConcurrencyPolicy) gin.HandlerFunc {
+	policy = policy.withDefaults()
+	limiter := newAIMDLimiter(policy)
+	retryAfter := strconv.Itoa(int(math.Ceil(policy.RetryAfter.Seconds())))
+	exempt := make(map[string]bool, len(policy.ExemptRoutes))
+	for _, route := range policy.ExemptRoutes {
+		exempt[route] = true
+	}
+
+	return func(c *gin.Context) {
+		if exempt[c.FullPath()] {
+			c.Next()
+			return
+		}
+		priority := policy.priority(c)
+		limit, ok := limiter.acquire(priority)
+		if !ok {
+			ctx := c.Request.Context()
+			trace.SpanFromContext(ctx).SetAttributes(attribute.Bool("http.load_shed", true))
+			logs.WithContext(ctx).Warn("Request shed, concurrency limit reached",
+				zap.String("route", c.FullPath()),
+				zap.String("priority", string(priority)),
+				zap.Int("limit", limit),
+			)
+			c.Header("Retry-After", retryAfter)
+			response.SendHTTPError(c, response.Unavailable("Service is overloaded, please retry"))
+			c.Abort()
+			return
+		}
+
+		start := time.Now()
+		defer func() {
+			overloaded := c.Writer.Status() == http.StatusGatewayTimeout
+			limiter.release(time.Since(start), overloaded, time.Now())
+		}()
+		c.Next()
+	}
+}
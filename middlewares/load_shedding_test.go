@@ -0,0 +1,111 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Faze-Technologies/go-utils/logs"
+	"github.com/gin-gonic/gin"
+)
+
+func TestAIMDLimiter(t *testing.T) {
+	l := newAIMDLimiter(ConcurrencyPolicy{InitialLimit: 10, MinLimit: 4, MaxLimit: 12}.withDefaults())
+	now := time.Now()
+
+	// Low priority may use half the limit, critical all of it.
+	for i := 0; i < 5; i++ {
+		if _, ok := l.acquire(PriorityLow); !ok {
+			t.Fatalf("low request %d rejected", i)
+		}
+	}
+	if _, ok := l.acquire(PriorityLow); ok {
+		t.Fatal("low request admitted past its share")
+	}
+	for i := 0; i < 5; i++ {
+		if _, ok := l.acquire(PriorityCritical); !ok {
+			t.Fatalf("critical request %d rejected", i)
+		}
+	}
+	if _, ok := l.acquire(PriorityCritical); ok {
+		t.Fatal("critical request admitted past the limit")
+	}
+
+	// Fast completions under load grow the limit up to MaxLimit.
+	for i := 0; i < 5; i++ {
+		l.release(time.Millisecond, false, now)
+	}
+	if l.limit != 12 {
+		t.Fatalf("limit = %v, want 12", l.limit)
+	}
+
+	// Slow completions back off once per threshold window.
+	l.release(time.Second, false, now)
+	l.release(time.Second, false, now)
+	if l.limit != 12*0.9 {
+		t.Fatalf("limit = %v, want one backoff to %v", l.limit, 12*0.9)
+	}
+	for i := 0; i < 3; i++ {
+		now = now.Add(time.Second)
+		l.release(0, true, now)
+	}
+	if l.limit < 4 || l.inFlight != 0 {
+		t.Fatalf("limit = %v inFlight = %d, want limit >= MinLimit and nothing in flight", l.limit, l.inFlight)
+	}
+}
+
+func TestAdaptiveConcurrency(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	logs.NewLogger()
+
+	release := make(chan struct{})
+	var started sync.WaitGroup
+	r := gin.New()
+	r.Use(AdaptiveConcurrency(ConcurrencyPolicy{
+		InitialLimit: 2,
+		MinLimit:     2,
+		MaxLimit:     2,
+		RetryAfter:   3 * time.Second,
+		Priorities:   map[string]RoutePriority{"POST /pay": PriorityCritical},
+	}))
+	r.GET("/slow", func(c *gin.Context) {
+		started.Done()
+		<-release
+		c.Status(http.StatusOK)
+	})
+	r.POST("/pay", func(c *gin.Context) { c.Status(http.StatusOK) })
+	r.GET("/healthz/ready", func(c *gin.Context) { c.Status(http.StatusServiceUnavailable) })
+
+	// A limit of 2 gives normal routes one slot; hold it.
+	started.Add(1)
+	done := make(chan struct{})
+	go func() {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/slow", nil))
+		close(done)
+	}()
+	started.Wait()
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/slow", nil))
+	if w.Code != http.StatusServiceUnavailable || w.Header().Get("Retry-After") != "3" {
+		t.Fatalf("normal request: got %d Retry-After %q, want 503 with Retry-After 3", w.Code, w.Header().Get("Retry-After"))
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/pay", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("critical request: got %d, want 200", w.Code)
+	}
+
+	// Probes are exempt, so the handler's own 503 comes through.
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/healthz/ready", nil))
+	if w.Code != http.StatusServiceUnavailable || w.Header().Get("Retry-After") != "" {
+		t.Fatalf("readiness probe: got %d Retry-After %q, want the handler's 503", w.Code, w.Header().Get("Retry-After"))
+	}
+
+	close(release)
+	<-done
+}
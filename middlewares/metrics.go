@@ -0,0 +1,120 @@
+package middlewares
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Faze-Technologies/go-utils/logs"
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.uber.org/zap"
+)
+
+// unmatchedRoute labels requests that did not match any route, so 404 scans
+// cannot blow up label cardinality.
+const unmatchedRoute = "unmatched"
+
+var durationBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+type httpServerMetrics struct {
+	requests     metric.Int64Counter
+	duration     metric.Float64Histogram
+	active       metric.Int64UpDownCounter
+	requestSize  metric.Int64Histogram
+	responseSize metric.Int64Histogram
+}
+
+func newHTTPServerMetrics(meter metric.Meter) (*httpServerMetrics, error) {
+	m := &httpServerMetrics{}
+	var err error
+	if m.requests, err = meter.Int64Counter("http.server.request.count",
+		metric.WithDescription("Number of HTTP requests served."),
+		metric.WithUnit("{request}")); err != nil {
+		return nil, err
+	}
+	if m.duration, err = meter.Float64Histogram("http.server.request.duration",
+		metric.WithDescription("Duration of HTTP requests."),
+		metric.WithUnit("s"),
+		metric.WithExplicitBucketBoundaries(durationBuckets...)); err != nil {
+		return nil, err
+	}
+	if m.active, err = meter.Int64UpDownCounter("http.server.active_requests",
+		metric.WithDescription("Number of HTTP requests in flight."),
+		metric.WithUnit("{request}")); err != nil {
+		return nil, err
+	}
+	if m.requestSize, err = meter.Int64Histogram("http.server.request.body.size",
+		metric.WithDescription("Size of HTTP request bodies."),
+		metric.WithUnit("By")); err != nil {
+		return nil, err
+	}
+	if m.responseSize, err = meter.Int64Histogram("http.server.response.body.size",
+		metric.WithDescription("Size of HTTP response bodies."),
+		metric.WithUnit("By")); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// metricsMethod bounds the method label to the standard verbs.
+func metricsMethod(method string) string {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch,
+		http.MethodDelete, http.MethodConnect, http.MethodOptions, http.MethodTrace:
+		return method
+	}
+	return "_OTHER"
+}
+
+func metricsRoute(c *gin.Context) string {
+	if route := c.FullPath(); route != "" {
+		return route
+	}
+	return unmatchedRoute
+}
+
+func statusClass(status int) string {
+	return strconv.Itoa(status/100) + "xx"
+}
+
+// HTTPMetrics records RED metrics for every request through the global OTel
+// MeterProvider: a request counter, a duration histogram, an in-flight
+// gauge and request/response body sizes. They are labelled by route
+// template (c.FullPath(), or "unmatched"), method and status class. The
+// measurements are recorded with the request context, so an SDK with
+// trace-based exemplars links them to the request's trace. Register it
+// after otelgin so the span is in context.
+func HTTPMetrics() gin.HandlerFunc {
+	m, err := newHTTPServerMetrics(otel.Meter("github.com/Faze-Technologies/go-utils/middlewares"))
+	if err != nil {
+		logs.GetLogger().Error("Error creating HTTP server metrics, metrics disabled", zap.Error(err))
+		return func(c *gin.Context) { c.Next() }
+	}
+
+	return func(c *gin.Context) {
+		start := time.Now()
+		ctx := c.Request.Context()
+		route := attribute.String("http.route", metricsRoute(c))
+		method := attribute.String("http.request.method", metricsMethod(c.Request.Method))
+
+		inFlight := metric.WithAttributes(route, method)
+		m.active.Add(ctx, 1, inFlight)
+		defer m.active.Add(ctx, -1, inFlight)
+
+		c.Next()
+
+		attrs := metric.WithAttributes(route, method,
+			attribute.String("http.response.status_class", statusClass(c.Writer.Status())))
+		m.requests.Add(ctx, 1, attrs)
+		m.duration.Record(ctx, time.Since(start).Seconds(), attrs)
+		if size := c.Request.ContentLength; size >= 0 {
+			m.requestSize.Record(ctx, size, attrs)
+		}
+		if size := c.Writer.Size(); size >= 0 {
+			m.responseSize.Record(ctx, int64(size), attrs)
+		}
+	}
+}
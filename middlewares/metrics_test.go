@@ -0,0 +1,78 @@
+package middlewares
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+func TestHTTPMetrics(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	previous := otel.GetMeterProvider()
+	otel.SetMeterProvider(sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)))
+	t.Cleanup(func() { otel.SetMeterProvider(previous) })
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(HTTPMetrics())
+	r.GET("/users/:id", func(c *gin.Context) { c.String(http.StatusOK, "ok") })
+	r.POST("/users/:id", func(c *gin.Context) { c.Status(http.StatusBadRequest) })
+
+	for _, req := range []*http.Request{
+		httptest.NewRequest(http.MethodGet, "/users/1", nil),
+		httptest.NewRequest(http.MethodGet, "/users/2", nil),
+		httptest.NewRequest(http.MethodPost, "/users/3", nil),
+		httptest.NewRequest(http.MethodGet, "/wp-admin/setup.php", nil),
+		httptest.NewRequest("PURGE", "/users/4", nil),
+	} {
+		r.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(context.Background(), &rm); err != nil {
+		t.Fatal(err)
+	}
+	counts := map[[3]string]int64{}
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			if m.Name != "http.server.request.count" {
+				continue
+			}
+			for _, dp := range m.Data.(metricdata.Sum[int64]).DataPoints {
+				route, _ := dp.Attributes.Value("http.route")
+				method, _ := dp.Attributes.Value("http.request.method")
+				class, _ := dp.Attributes.Value("http.response.status_class")
+				counts[[3]string{route.AsString(), method.AsString(), class.AsString()}] = dp.Value
+			}
+		}
+	}
+
+	want := map[[3]string]int64{
+		{"/users/:id", http.MethodGet, "2xx"}:   2,
+		{"/users/:id", http.MethodPost, "4xx"}:  1,
+		{unmatchedRoute, http.MethodGet, "4xx"}: 1,
+		{unmatchedRoute, "_OTHER", "4xx"}:       1,
+	}
+	if len(counts) != len(want) {
+		t.Errorf("request.count series = %v, want %v", counts, want)
+	}
+	for labels, n := range want {
+		if counts[labels] != n {
+			t.Errorf("request.count%v = %d, want %d", labels, counts[labels], n)
+		}
+	}
+}
+
+func TestStatusClass(t *testing.T) {
+	for status, want := range map[int]string{200: "2xx", 204: "2xx", 301: "3xx", 404: "4xx", 503: "5xx"} {
+		if got := statusClass(status); got != want {
+			t.Errorf("statusClass(%d) = %q, want %q", status, got, want)
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package middlewares
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/Faze-Technologies/go-utils/logs"
+	"github.com/Faze-Technologies/go-utils/request"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v4"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+// mfaAuthenticatedAtClaim is the access-token claim the auth service sets
+// (unix seconds) when the token was issued right after an MFA challenge.
+const mfaAuthenticatedAtClaim = "mfa_at"
+
+// maxStepUpGrantTTL bounds how long a step-up grant lives in Redis. Routes
+// asking for an older MFA than this will always re-challenge.
+const maxStepUpGrantTTL = 24 * time.Hour
+
+// stepUpStore holds step-up grants; *cache.Cache satisfies it.
+type stepUpStore interface {
+	Get(ctx context.Context, key string) (string, error)
+	Set(ctx context.Context, key string, value string, expiration time.Duration) error
+}
+
+// stepUps returns where grants are stored, or nil when the Middlewares have
+// no cache, as with authtest.Kit.
+func (m *Middlewares) stepUps() stepUpStore {
+	if m.stepUpGrants != nil {
+		return m.stepUpGrants
+	}
+	if m.Cache == nil {
+		return nil
+	}
+	return m.Cache
+}
+
+func stepUpKey(sessionId string) string {
+	return fmt.Sprintf("auth:mfa:stepup:%s", sessionId)
+}
+
+// sessionID identifies the login session of a token: the sid claim when the
+// auth service sets one, otherwise the token's jti.
+func sessionID(claims jwt.MapClaims) string {
+	if sid, ok := claims["sid"].(string); ok && sid != "" {
+		return sid
+	}
+	jti, _ := claims["jti"].(string)
+	return jti
+}
+
+// RecordMFAStepUp stores a step-up grant for the session of the current
+// request. Call it from the handler that verifies the MFA code so the
+// session passes RequireRecentMFA without a new token being minted.
+func (m *Middlewares) RecordMFAStepUp(c *gin.Context) error {
+	sid := sessionID(GetTokenClaims(c))
+	if sid == "" {
+		return errors.New("mfa: token has no session id")
+	}
+	return m.RecordMFAStepUpForSession(c.Request.Context(), sid, time.Now())
+}
+
+// RecordMFAStepUpForSession stores a step-up grant for sessionId at time at.
+func (m *Middlewares) RecordMFAStepUpForSession(ctx context.Context, sessionId string, at time.Time) error {
+	store := m.stepUps()
+	if store == nil {
+		return errors.New("mfa: no cache configured for step-up grants")
+	}
+	return store.Set(ctx, stepUpKey(sessionId), strconv.FormatInt(at.Unix(), 10), maxStepUpGrantTTL)
+}
+
+// lastMFAAt returns the most recent MFA time known for the request, from the
+// token claim or a stored step-up grant, whichever is newer. Without a cache
+// only the claim is used.
+func (m *Middlewares) lastMFAAt(ctx context.Context, claims jwt.MapClaims) time.Time {
+	last := claimTime(claims, mfaAuthenticatedAtClaim)
+
+	sid := sessionID(claims)
+	store := m.stepUps()
+	if sid == "" || store == nil {
+		return last
+	}
+	raw, err := store.Get(ctx, stepUpKey(sid))
+	if err != nil {
+		return last
+	}
+	secs, err := strconv.ParseInt(raw, 10, 64)
+	if err != nil {
+		logs.WithContext(ctx).Warn("Ignoring unreadable MFA step-up grant", zap.String("sessionId", sid))
+		return last
+	}
+	if granted := time.Unix(secs, 0); granted.After(last) {
+		return granted
+	}
+	return last
+}
+
+// RequireRecentMFA only lets the request through if the user completed MFA
+// within maxAge, either when the token was issued or through a step-up
+// recorded with RecordMFAStepUp. Otherwise it returns the standard MFA error
+// carrying the user's mfaMethod so the app can show the right challenge.
+// It must run after AuthenticateUser.
+func (m *Middlewares) RequireRecentMFA(maxAge time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+
+		user, sErr := GetAuthUser(c)
+		if sErr != nil {
+			request.SendServiceError(c, sErr)
+			c.Abort()
+			return
+		}
+
+		last := m.lastMFAAt(ctx, GetTokenClaims(c))
+		fresh := !last.IsZero() && time.Since(last) <= maxAge
+
+		span := trace.SpanFromContext(ctx)
+		span.SetAttributes(attribute.Bool("auth.mfa_fresh", fresh))
+		if fresh {
+			c.Next()
+			return
+		}
+
+		logs.WithContext(ctx).Info("Step-up MFA required",
+			zap.String("userId", user.Id),
+			zap.Duration("maxAge", maxAge),
+			zap.Time("lastMfaAt", last),
+		)
+		request.SendServiceError(c, request.CreateMFAError(nil, gin.H{"mfaMethod": user.MfaMethod}))
+		c.Abort()
+	}
+}
@@ -0,0 +1,153 @@
+package middlewares
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Faze-Technologies/go-utils/logs"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// memoryStepUps is an in-memory stepUpStore.
+type memoryStepUps struct {
+	mu     sync.Mutex
+	values map[string]string
+}
+
+func (m *memoryStepUps) Get(_ context.Context, key string) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	v, ok := m.values[key]
+	if !ok {
+		return "", errors.New("key not found")
+	}
+	return v, nil
+}
+
+func (m *memoryStepUps) Set(_ context.Context, key string, value string, _ time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.values[key] = value
+	return nil
+}
+
+// mfaRouter serves /withdraw behind RequireRecentMFA(10m) and /stepup, which
+// records a grant, for whichever claims the test sets.
+func mfaRouter(m *Middlewares, claims *jwt.MapClaims) *gin.Engine {
+	authenticate := func(c *gin.Context) {
+		user := UserDetails{Id: "u1", MfaMethod: "totp"}
+		c.Request = c.Request.WithContext(withAuthenticatedUser(c.Request.Context(), user, *claims, "token"))
+	}
+	r := gin.New()
+	r.GET("/withdraw", authenticate, m.RequireRecentMFA(10*time.Minute), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	r.POST("/stepup", authenticate, func(c *gin.Context) {
+		if err := m.RecordMFAStepUp(c); err != nil {
+			c.Status(http.StatusBadRequest)
+			return
+		}
+		c.Status(http.StatusNoContent)
+	})
+	return r
+}
+
+func serveMFA(r *gin.Engine, method, path string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(method, path, nil))
+	return w
+}
+
+func TestRequireRecentMFA(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	logs.NewLogger()
+
+	now := time.Now()
+	cases := []struct {
+		name   string
+		claims jwt.MapClaims
+		status int
+	}{
+		{"fresh claim", jwt.MapClaims{"sid": "s1", mfaAuthenticatedAtClaim: float64(now.Add(-time.Minute).Unix())}, http.StatusOK},
+		{"stale claim", jwt.MapClaims{"sid": "s2", mfaAuthenticatedAtClaim: float64(now.Add(-time.Hour).Unix())}, http.StatusPreconditionRequired},
+		{"missing claim", jwt.MapClaims{"jti": "t3"}, http.StatusPreconditionRequired},
+		{"no session", jwt.MapClaims{mfaAuthenticatedAtClaim: float64(now.Add(-time.Minute).Unix())}, http.StatusOK},
+	}
+	for _, tc := range cases {
+		m := &Middlewares{stepUpGrants: &memoryStepUps{values: map[string]string{}}}
+		w := serveMFA(mfaRouter(m, &tc.claims), http.MethodGet, "/withdraw")
+		if w.Code != tc.status {
+			t.Errorf("%s: status = %d, want %d", tc.name, w.Code, tc.status)
+			continue
+		}
+		if w.Code == http.StatusOK {
+			continue
+		}
+		var body struct {
+			Error string            `json:"error"`
+			Data  map[string]string `json:"data"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+			t.Fatalf("%s: decoding %s: %v", tc.name, w.Body.String(), err)
+		}
+		if body.Error != "mfaRequiredError" || body.Data["mfaMethod"] != "totp" {
+			t.Errorf("%s: body = %s, want the MFA error with mfaMethod", tc.name, w.Body.String())
+		}
+	}
+}
+
+func TestRequireRecentMFAStepUp(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	logs.NewLogger()
+
+	store := &memoryStepUps{values: map[string]string{}}
+	m := &Middlewares{stepUpGrants: store}
+	claims := jwt.MapClaims{"sid": "s1", "jti": "t1", mfaAuthenticatedAtClaim: float64(time.Now().Add(-time.Hour).Unix())}
+	r := mfaRouter(m, &claims)
+
+	if w := serveMFA(r, http.MethodGet, "/withdraw"); w.Code != http.StatusPreconditionRequired {
+		t.Fatalf("before step-up: status = %d, want %d", w.Code, http.StatusPreconditionRequired)
+	}
+	if w := serveMFA(r, http.MethodPost, "/stepup"); w.Code != http.StatusNoContent {
+		t.Fatalf("RecordMFAStepUp: status = %d", w.Code)
+	}
+	if _, ok := store.values[stepUpKey("s1")]; !ok {
+		t.Errorf("grant not stored under the sid: %v", store.values)
+	}
+	if w := serveMFA(r, http.MethodGet, "/withdraw"); w.Code != http.StatusOK {
+		t.Errorf("after step-up: status = %d, want the grant to win over the older claim", w.Code)
+	}
+
+	// A token for another session of the same user does not inherit it.
+	claims = jwt.MapClaims{"sid": "s2", mfaAuthenticatedAtClaim: float64(time.Now().Add(-time.Hour).Unix())}
+	if w := serveMFA(r, http.MethodGet, "/withdraw"); w.Code != http.StatusPreconditionRequired {
+		t.Errorf("other session: status = %d, want %d", w.Code, http.StatusPreconditionRequired)
+	}
+
+	// Without sid or jti there is nothing to attach a grant to.
+	claims = jwt.MapClaims{}
+	if w := serveMFA(r, http.MethodPost, "/stepup"); w.Code != http.StatusBadRequest {
+		t.Errorf("step-up without a session: status = %d, want it refused", w.Code)
+	}
+}
+
+func TestRequireRecentMFAWithoutCache(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	logs.NewLogger()
+
+	claims := jwt.MapClaims{"sid": "s1", mfaAuthenticatedAtClaim: float64(time.Now().Unix())}
+	r := mfaRouter(&Middlewares{}, &claims)
+	if w := serveMFA(r, http.MethodGet, "/withdraw"); w.Code != http.StatusOK {
+		t.Errorf("status = %d, want the claim honoured without a cache", w.Code)
+	}
+	if w := serveMFA(r, http.MethodPost, "/stepup"); w.Code != http.StatusBadRequest {
+		t.Errorf("RecordMFAStepUp without a cache: status = %d, want an error", w.Code)
+	}
+}
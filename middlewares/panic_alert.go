@@ -0,0 +1,190 @@
+package middlewares
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Faze-Technologies/go-utils/config"
+	"github.com/Faze-Technologies/go-utils/redact"
+	"github.com/Faze-Technologies/go-utils/utils"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// Panic alert defaults, overridable under alerts.panic.
+const (
+	defaultPanicAlertDedupWindow = 10 * time.Minute
+	defaultPanicAlertsPerMinute  = 5
+	panicAlertTimeout            = 5 * time.Second
+	panicDumpMaxBodyBytes        = 8 * 1024
+	defaultPanicAlertSender      = "panic-alert"
+)
+
+// panicAlertConfig configures Slack alerts from GinRecovery:
+//
+//	alerts:
+//	  panic:
+//	    slack_channel: "#svc-alerts"      # empty disables alerts
+//	    sender: "wallet-service"         # defaults to service_auth.name
+//	    dedup_seconds: 600                # one alert per panic site per window
+//	    max_per_minute: 5                 # across all sites
+//	    trace_url: "https://signoz.example.com/trace/{traceId}"
+type panicAlertConfig struct {
+	channel      string
+	sender       string
+	dedupWindow  time.Duration
+	perMinute    int
+	traceURLTmpl string
+}
+
+func loadPanicAlertConfig() panicAlertConfig {
+	cfg := panicAlertConfig{
+		channel:      config.GetString("alerts.panic.slack_channel"),
+		sender:       config.GetString("alerts.panic.sender"),
+		dedupWindow:  defaultPanicAlertDedupWindow,
+		perMinute:    defaultPanicAlertsPerMinute,
+		traceURLTmpl: config.GetString("alerts.panic.trace_url"),
+	}
+	if cfg.sender == "" {
+		cfg.sender = config.GetString("service_auth.name")
+	}
+	if cfg.sender == "" {
+		cfg.sender = defaultPanicAlertSender
+	}
+	if config.Get("alerts.panic.dedup_seconds") != nil {
+		cfg.dedupWindow = time.Duration(config.GetInt("alerts.panic.dedup_seconds")) * time.Second
+	}
+	if config.Get("alerts.panic.max_per_minute") != nil {
+		cfg.perMinute = config.GetInt("alerts.panic.max_per_minute")
+	}
+	return cfg
+}
+
+// panicAlerter decides which panics reach Slack: each panic site alerts at
+// most once per dedup window, and all sites together at most perMinute
+// times a minute, so a hot panic loop cannot flood the channel.
+type panicAlerter struct {
+	cfg      panicAlertConfig
+	logger   *zap.Logger
+	redactor *redact.Redactor
+
+	mu          sync.Mutex
+	lastBySite  map[string]time.Time
+	windowStart time.Time
+	sentInWin   int
+}
+
+func newPanicAlerter(logger *zap.Logger, redactor *redact.Redactor) *panicAlerter {
+	return &panicAlerter{cfg: loadPanicAlertConfig(), logger: logger, redactor: redactor, lastBySite: map[string]time.Time{}}
+}
+
+func (a *panicAlerter) allow(site string, now time.Time) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if last, ok := a.lastBySite[site]; ok && now.Sub(last) < a.cfg.dedupWindow {
+		return false
+	}
+	if now.Sub(a.windowStart) >= time.Minute {
+		a.windowStart, a.sentInWin = now, 0
+	}
+	if a.sentInWin >= a.cfg.perMinute {
+		return false
+	}
+	a.sentInWin++
+	a.lastBySite[site] = now
+
+	// Forget sites outside the window so the map stays small.
+	for s, t := range a.lastBySite {
+		if now.Sub(t) >= a.cfg.dedupWindow {
+			delete(a.lastBySite, s)
+		}
+	}
+	return true
+}
+
+func (a *panicAlerter) traceURL(traceId string) string {
+	if a.cfg.traceURLTmpl == "" || traceId == "" {
+		return traceId
+	}
+	return strings.ReplaceAll(a.cfg.traceURLTmpl, "{traceId}", traceId)
+}
+
+// alert sends the Slack message in the background; the request has already
+// failed and must not wait on Slack. Panic values often embed the data being
+// processed, so the value is redacted like a body fragment before it leaves
+// the service.
+func (a *panicAlerter) alert(ctx context.Context, site, method, route string, p interface{}, traceId string) {
+	if a.cfg.channel == "" || !a.allow(site, time.Now()) {
+		return
+	}
+	message := a.message(site, method, route, p, traceId)
+
+	ctx = context.WithoutCancel(ctx)
+	go func() {
+		ctx, cancel := context.WithTimeout(ctx, panicAlertTimeout)
+		defer cancel()
+		utils.SendSlackAlert(ctx, a.logger, a.cfg.channel, message, a.cfg.sender)
+	}()
+}
+
+func (a *panicAlerter) message(site, method, route string, p interface{}, traceId string) string {
+	message := fmt.Sprintf(":rotating_light: *Panic in %s*\n*Route:* %s %s\n*Site:* `%s`\n*Error:* %s",
+		a.cfg.sender, method, route, site, a.redactor.Partial(fmt.Sprint(p)))
+	if traceId != "" {
+		message += "\n*Trace:* " + a.traceURL(traceId)
+	}
+	return message
+}
+
+// panicSite returns "function file:line" for the frame that panicked: the
+// first frame below runtime.gopanic, skipping runtime internals.
+func panicSite() string {
+	pcs := make([]uintptr, 32)
+	n := runtime.Callers(1, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+
+	sawPanic := false
+	for {
+		frame, more := frames.Next()
+		if frame.Function == "runtime.gopanic" {
+			sawPanic = true
+		} else if sawPanic && !strings.HasPrefix(frame.Function, "runtime.") {
+			return fmt.Sprintf("%s %s:%d", frame.Function, frame.File, frame.Line)
+		}
+		if !more {
+			return "unknown"
+		}
+	}
+}
+
+// redactedRequestDump describes the request for panic logs without leaking
+// credentials: headers and query go through the redactor and at most
+// panicDumpMaxBodyBytes of any unread body is included.
+func redactedRequestDump(c *gin.Context, redactor *redact.Redactor) []zap.Field {
+	fields := []zap.Field{
+		zap.String("method", c.Request.Method),
+		zap.String("path", c.Request.URL.Path),
+		zap.String("route", c.FullPath()),
+		zap.String("query", redactor.Query(c.Request.URL.RawQuery)),
+		zap.String("headers", redactor.HeadersJSON(c.Request.Header)),
+	}
+	if c.Request.Body == nil {
+		return fields
+	}
+	body, _ := io.ReadAll(io.LimitReader(c.Request.Body, panicDumpMaxBodyBytes+1))
+	if len(body) == 0 {
+		return fields
+	}
+	truncated := len(body) > panicDumpMaxBodyBytes
+	if truncated {
+		body = body[:panicDumpMaxBodyBytes]
+	}
+	return append(fields, zap.String("body",
+		renderCapturedBody(redactor, body, truncated, c.Request.Header.Get("Content-Type"))))
+}
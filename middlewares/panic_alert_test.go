@@ -0,0 +1,67 @@
+package middlewares
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Faze-Technologies/go-utils/redact"
+)
+
+func TestPanicSite(t *testing.T) {
+	var site string
+	func() {
+		defer func() {
+			recover()
+			site = panicSite()
+		}()
+		panickingHandler()
+	}()
+	if !strings.Contains(site, "panickingHandler") {
+		t.Errorf("panicSite() = %q, want the panicking function", site)
+	}
+}
+
+func panickingHandler() {
+	var m map[string]int
+	m["boom"] = 1
+}
+
+func TestPanicAlerterDedupAndRateLimit(t *testing.T) {
+	a := &panicAlerter{
+		cfg:        panicAlertConfig{dedupWindow: time.Minute, perMinute: 2},
+		lastBySite: map[string]time.Time{},
+	}
+	now := time.Now()
+
+	if !a.allow("a", now) {
+		t.Fatal("first alert for a site was suppressed")
+	}
+	if a.allow("a", now.Add(time.Second)) {
+		t.Error("repeat alert within the dedup window was sent")
+	}
+	if !a.allow("b", now.Add(time.Second)) {
+		t.Error("alert for a second site was suppressed")
+	}
+	if a.allow("c", now.Add(2*time.Second)) {
+		t.Error("alert over the per-minute limit was sent")
+	}
+	if !a.allow("a", now.Add(61*time.Second)) {
+		t.Error("alert after the dedup window was suppressed")
+	}
+}
+
+func TestPanicAlertMessageRedactsValue(t *testing.T) {
+	a := &panicAlerter{cfg: panicAlertConfig{sender: "wallet"}, redactor: redact.Default()}
+	p := fmt.Errorf(`decode {"password":"hunter2","email":"jane@example.com"}: unexpected EOF`)
+	message := a.message("main.handler x.go:1", "POST", "/login", p, "")
+	for _, leaked := range []string{"hunter2", "jane@example.com"} {
+		if strings.Contains(message, leaked) {
+			t.Errorf("alert leaks %q: %s", leaked, message)
+		}
+	}
+	if !strings.Contains(message, "unexpected EOF") {
+		t.Errorf("alert lost the error: %s", message)
+	}
+}
@@ -0,0 +1,106 @@
+package middlewares
+
+import (
+	"context"
+	"errors"
+
+	"github.com/Faze-Technologies/go-utils/apikey"
+	"github.com/Faze-Technologies/go-utils/logs"
+	"github.com/Faze-Technologies/go-utils/response"
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+const partnerContextKey contextKey = "partner"
+
+// APIKeyHeader carries the raw partner API key.
+const APIKeyHeader = "X-Api-Key"
+
+type PartnerDetails struct {
+	KeyId       string   `json:"keyId"`
+	PartnerId   string   `json:"partnerId"`
+	PartnerName string   `json:"partnerName"`
+	Scopes      []string `json:"scopes"`
+}
+
+func GetAuthPartner(c *gin.Context) (*PartnerDetails, *response.ServiceError) {
+	partner, ok := c.Request.Context().Value(partnerContextKey).(PartnerDetails)
+	if !ok {
+		return nil, response.Unauthenticated("Partner is not authenticated")
+	}
+	return &partner, nil
+}
+
+// AuthenticatePartner authenticates the X-Api-Key header through keys,
+// enforces the key's quotas and requires every scope in requiredScopes. On
+// success the PartnerDetails principal is stored in the request context.
+func AuthenticatePartner(keys *apikey.Service, requiredScopes ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+		logger := logs.WithContext(ctx)
+
+		rawKey := c.GetHeader(APIKeyHeader)
+		if rawKey == "" {
+			response.SendHTTPError(c, response.Unauthenticated("API key required"))
+			c.Abort()
+			return
+		}
+
+		key, err := keys.Authenticate(ctx, rawKey)
+		if err != nil {
+			if errors.Is(err, apikey.ErrKeyNotFound) || errors.Is(err, apikey.ErrKeyInactive) {
+				logger.Info("Rejected API key", zap.Error(err))
+				response.SendHTTPError(c, response.Unauthenticated("Invalid API key"))
+			} else {
+				logger.Error("Error authenticating API key", zap.Error(err))
+				response.SendHTTPError(c, response.InternalWrap(err, "Internal Server Error"))
+			}
+			c.Abort()
+			return
+		}
+
+		span := trace.SpanFromContext(ctx)
+		span.SetAttributes(
+			attribute.String("partner.id", key.PartnerId),
+			attribute.String("partner.key_id", key.Id),
+		)
+
+		for _, scope := range requiredScopes {
+			if !key.HasScope(scope) {
+				logger.Info("API key missing scope", zap.String("keyId", key.Id), zap.String("scope", scope))
+				response.SendHTTPError(c, response.PermissionDeniedf("API key lacks scope %s", scope))
+				c.Abort()
+				return
+			}
+		}
+
+		if err := keys.Consume(ctx, key); err != nil {
+			switch {
+			case errors.Is(err, apikey.ErrDailyQuotaExceeded):
+				logger.Warn("API key quota exceeded", zap.String("keyId", key.Id), zap.Error(err))
+				response.SendHTTPError(c, response.New(response.CodeResourceExhausted, "Quota exceeded").
+					WithDetails("quota", "daily"))
+			case errors.Is(err, apikey.ErrMonthlyQuotaExceeded):
+				logger.Warn("API key quota exceeded", zap.String("keyId", key.Id), zap.Error(err))
+				response.SendHTTPError(c, response.New(response.CodeResourceExhausted, "Quota exceeded").
+					WithDetails("quota", "monthly"))
+			default:
+				logger.Error("Error counting API key usage", zap.String("keyId", key.Id), zap.Error(err))
+				response.SendHTTPError(c, response.InternalWrap(err, "Internal Server Error"))
+			}
+			c.Abort()
+			return
+		}
+
+		partner := PartnerDetails{
+			KeyId:       key.Id,
+			PartnerId:   key.PartnerId,
+			PartnerName: key.PartnerName,
+			Scopes:      key.Scopes,
+		}
+		c.Request = c.Request.WithContext(context.WithValue(ctx, partnerContextKey, partner))
+		c.Next()
+	}
+}
@@ -7,10 +7,15 @@ import (
 	"github.com/mileusna/useragent"
 )
 
+// ParseUserAgent stores the request's ClientInfo in its context, read with
+// ClientInfoFromContext or GetClientInfo. The raw useragent.UserAgent is
+// still stored under "parsedUA" for existing readers; new code should use
+// ClientInfo.
 func ParseUserAgent() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		parsedUA := useragent.Parse(c.Request.Header.Get("User-Agent"))
-		ctx := context.WithValue(c.Request.Context(), "parsedUA", parsedUA)
+		parsedUA := useragent.Parse(c.Request.UserAgent())
+		ctx := context.WithValue(c.Request.Context(), clientInfoContextKey, newClientInfo(c, parsedUA))
+		ctx = context.WithValue(ctx, "parsedUA", parsedUA)
 		c.Request = c.Request.WithContext(ctx)
 		c.Next()
 	}
@@ -0,0 +1,26 @@
+package middlewares
+
+import (
+	"github.com/Faze-Technologies/go-utils/requestid"
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// RequestID adopts the caller's X-Request-Id, or generates one, and stores
+// it in the request context so logs.WithContext, outbound resty and gRPC
+// calls and Pub/Sub publishes pick it up. The id is echoed in the response
+// header and set as the request.id span attribute. Register it before
+// GinLogger so the access log carries the id too.
+func RequestID(c *gin.Context) {
+	id := c.GetHeader(requestid.Header)
+	if !requestid.Valid(id) {
+		id = requestid.New()
+	}
+
+	ctx := requestid.NewContext(c.Request.Context(), id)
+	c.Request = c.Request.WithContext(ctx)
+	c.Header(requestid.Header, id)
+	trace.SpanFromContext(ctx).SetAttributes(attribute.String("request.id", id))
+	c.Next()
+}
@@ -0,0 +1,47 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Faze-Technologies/go-utils/requestid"
+	"github.com/gin-gonic/gin"
+)
+
+func TestRequestID(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(RequestID)
+	var seen string
+	r.GET("/", func(c *gin.Context) { seen = requestid.FromContext(c.Request.Context()) })
+
+	cases := []struct {
+		name   string
+		header string
+		adopt  bool
+	}{
+		{"adopts caller id", "req-from-gateway", true},
+		{"generates when missing", "", false},
+		{"replaces invalid id", "bad id\r\nx: y", false},
+	}
+	for _, tc := range cases {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		if tc.header != "" {
+			req.Header.Set(requestid.Header, tc.header)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		echoed := w.Header().Get(requestid.Header)
+		if echoed != seen {
+			t.Errorf("%s: response id %q, context id %q", tc.name, echoed, seen)
+		}
+		if tc.adopt && seen != tc.header {
+			t.Errorf("%s: id = %q, want %q", tc.name, seen, tc.header)
+		}
+		if !tc.adopt && (seen == tc.header || !requestid.Valid(seen)) {
+			t.Errorf("%s: id = %q, want a generated id", tc.name, seen)
+		}
+	}
+}
@@ -1,18 +1,17 @@
 package middlewares
 
 import (
-	"bytes"
 	"encoding/json"
-	"io"
+	"fmt"
 	"net"
 	"net/http"
-	"net/http/httputil"
 	"os"
 	"runtime/debug"
 	"strings"
 	"time"
 
 	"github.com/Faze-Technologies/go-utils/logs"
+	"github.com/Faze-Technologies/go-utils/redact"
 	"github.com/Faze-Technologies/go-utils/request"
 	"github.com/gin-gonic/gin"
 	"go.opentelemetry.io/otel/attribute"
@@ -22,23 +21,6 @@ import (
 )
 
 
-// responseBodyWriter wraps gin.ResponseWriter to capture the response body.
-type responseBodyWriter struct {
-	gin.ResponseWriter
-	body *bytes.Buffer
-}
-
-func (r *responseBodyWriter) Write(b []byte) (int, error) {
-	r.body.Write(b)
-	return r.ResponseWriter.Write(b)
-}
-
-var sensitiveKeys = map[string]bool{
-	"password": true, "token": true, "otp": true,
-	"cardNumber": true, "cvv": true, "privateKey": true,
-	"secret": true, "authorization": true,
-}
-
 // resolveInternalUserID extracts userId from headers/query for internal service-to-service calls.
 // c.GetHeader is case-insensitive for hyphenated headers (user-id == User-Id == USER-ID),
 // but "userId" has a different canonical form so it needs its own check.
@@ -51,42 +33,40 @@ func resolveInternalUserID(c *gin.Context) string {
 	return c.Query("userId")
 }
 
-// redactBody parses JSON body and masks sensitive fields before attaching to spans/logs.
-func redactBody(body []byte) string {
-	var data map[string]any
-	if err := json.Unmarshal(body, &data); err != nil {
-		return "[non-json body]"
-	}
-	for key := range data {
-		if sensitiveKeys[strings.ToLower(key)] {
-			data[key] = "[REDACTED]"
-		}
+// newServiceRedactor builds the redactor for the service's redaction config,
+// falling back to the built-in rules if the config is invalid.
+func newServiceRedactor(logger *zap.Logger) *redact.Redactor {
+	redactor, err := redact.New(redact.ConfigFromService())
+	if err != nil {
+		logger.Error("Invalid redaction config, using defaults", zap.Error(err))
+		return redact.Default()
 	}
-	out, _ := json.Marshal(data)
-	return string(out)
+	return redactor
 }
 
 func GinLogger(logger *zap.Logger) gin.HandlerFunc {
+	redactor := newServiceRedactor(logger)
+	captureCfg := loadBodyCaptureConfig()
 	return func(c *gin.Context) {
 		start := time.Now()
 		path := c.Request.URL.Path
-		query := c.Request.URL.RawQuery
+		query := redactor.Query(c.Request.URL.RawQuery)
 		c.Set("logs", logger)
 
-		// Buffer request body so handler can still read it
-		var bodyBytes []byte
+		// Buffer a bounded prefix of the request body; the handler still
+		// reads all of it.
 		contentType := c.Request.Header.Get("Content-Type")
-		if c.Request.Body != nil && !strings.Contains(contentType, "multipart") {
-			bodyBytes, _ = io.ReadAll(c.Request.Body)
-			c.Request.Body = io.NopCloser(bytes.NewBuffer(bodyBytes))
-		}
+		capture := newBodyCapture(c, captureCfg)
 
-		// Wrap the response writer to capture the response body
-		respWriter := &responseBodyWriter{body: &bytes.Buffer{}, ResponseWriter: c.Writer}
+		// Wrap the response writer to capture a bounded response body
+		respWriter := &captureWriter{ResponseWriter: c.Writer, capture: capture, c: c}
 		c.Writer = respWriter
 
 		c.Next()
 
+		// Path parameters can carry identifiers or tokens, so the path is
+		// redacted once routing has bound them.
+		path = redactor.Path(path, c.FullPath())
 		statusCode := c.Writer.Status()
 		cost := time.Since(start)
 
@@ -129,20 +109,27 @@ func GinLogger(logger *zap.Logger) gin.HandlerFunc {
 			span.SetAttributes(attribute.String("user.id", id))
 		}
 
-		if v := c.GetHeader("appversion"); v != "" {
-			span.SetAttributes(attribute.String("app.version", v))
+		client := GetClientInfo(c)
+		if client.RawAppVersion != "" {
+			span.SetAttributes(attribute.String("app.version", client.RawAppVersion))
+		}
+		if client.AppID != "" {
+			span.SetAttributes(attribute.String("app.id", client.AppID))
 		}
-		if v := c.GetHeader("appid"); v != "" {
-			span.SetAttributes(attribute.String("app.id", v))
+		if client.Platform != "" {
+			span.SetAttributes(attribute.String("app.platform", client.Platform))
 		}
-		if v := c.GetHeader("source"); v != "" {
-			span.SetAttributes(attribute.String("app.platform", v))
+		if client.Bot {
+			span.SetAttributes(attribute.Bool("client.bot", true))
 		}
 
-		// Span event: request body, query, path params, response body on errors
+		// Span event: query, path params, headers on errors, and captured
+		// bodies for errors and sampled requests
 		var eventAttrs []attribute.KeyValue
-		if len(bodyBytes) > 0 {
-			eventAttrs = append(eventAttrs, attribute.String("http.request.body", redactBody(bodyBytes)))
+		reportBodies := capture.shouldReport(statusCode)
+		if reportBodies && len(capture.request) > 0 {
+			eventAttrs = append(eventAttrs, attribute.String("http.request.body",
+				renderCapturedBody(redactor, capture.request, capture.reqTrunc, contentType)))
 		}
 		if query != "" {
 			eventAttrs = append(eventAttrs, attribute.String("http.request.query", query))
@@ -150,16 +137,18 @@ func GinLogger(logger *zap.Logger) gin.HandlerFunc {
 		if params := c.Params; len(params) > 0 {
 			paramMap := make(map[string]string, len(params))
 			for _, p := range params {
-				paramMap[p.Key] = p.Value
+				paramMap[p.Key] = redactor.Param(p.Key, p.Value)
 			}
 			if b, err := json.Marshal(paramMap); err == nil {
 				eventAttrs = append(eventAttrs, attribute.String("http.request.params", string(b)))
 			}
 		}
 		if statusCode >= 400 {
-			if respBody := respWriter.body.Bytes(); len(respBody) > 0 {
-				eventAttrs = append(eventAttrs, attribute.String("http.response.body", string(respBody)))
-			}
+			eventAttrs = append(eventAttrs, attribute.String("http.request.headers", redactor.HeadersJSON(c.Request.Header)))
+		}
+		if reportBodies && respWriter.body.Len() > 0 {
+			eventAttrs = append(eventAttrs, attribute.String("http.response.body",
+				renderCapturedBody(redactor, respWriter.body.Bytes(), respWriter.truncated, c.Writer.Header().Get("Content-Type"))))
 		}
 		if len(eventAttrs) > 0 {
 			span.AddEvent("request.payload", trace.WithAttributes(eventAttrs...))
@@ -182,14 +171,39 @@ func GinLogger(logger *zap.Logger) gin.HandlerFunc {
 		if userID != "" {
 			logFields = append(logFields, zap.String("userId", userID))
 		}
+		if logs.CloudLoggingEnabled() {
+			requestURL := path
+			if query != "" {
+				requestURL += "?" + query
+			}
+			logFields = append(logFields, logs.HTTPRequestField(logs.HTTPRequest{
+				RequestMethod: c.Request.Method,
+				RequestURL:    requestURL,
+				RequestSize:   c.Request.ContentLength,
+				Status:        statusCode,
+				ResponseSize:  int64(c.Writer.Size()),
+				UserAgent:     c.Request.UserAgent(),
+				RemoteIP:      clientIP,
+				Referer:       c.Request.Referer(),
+				Protocol:      c.Request.Proto,
+				Latency:       cost,
+			}))
+		}
 		logs.WithContext(c.Request.Context()).Info(path, logFields...)
 	}
 }
 
+// GinRecovery turns a panic into a 500. The panic is written as one
+// structured log entry with a redacted request dump and panic value, recorded
+// on the active span with its stack, and alerted to Slack (see
+// panicAlertConfig) at most once per panic site per dedup window.
 func GinRecovery(logger *zap.Logger) gin.HandlerFunc {
+	redactor := newServiceRedactor(logger)
+	alerter := newPanicAlerter(logger, redactor)
 	return func(c *gin.Context) {
 		defer func() {
 			if err := recover(); err != nil {
+				ctx := c.Request.Context()
 				// Check for a broken connection, as it is not really a
 				// condition that warrants a panic stack trace.
 				var brokenPipe bool
@@ -201,18 +215,37 @@ func GinRecovery(logger *zap.Logger) gin.HandlerFunc {
 					}
 				}
 
-				httpRequest, _ := httputil.DumpRequest(c.Request, true)
 				if brokenPipe {
-					logger.Sugar().Error(c.Request.URL.Path, zap.Any("error", err))
-					logger.Sugar().Error(string(httpRequest))
+					logs.WithContext(ctx).Warn("Client connection closed",
+						append(redactedRequestDump(c, redactor), zap.Any("error", err))...)
 					// If the connection is dead, we can't write a status to it.
 					c.Error(err.(error))
 					c.Abort()
 					return
 				}
-				logger.Sugar().Error(err)
-				logger.Sugar().Error(string(debug.Stack()))
-				logger.Sugar().Error("[raw http request] ", string(httpRequest))
+
+				// The log entry, span and alert all carry the redacted value.
+				panicValue := redactor.Partial(fmt.Sprint(err))
+				site := panicSite()
+				stack := string(debug.Stack())
+				span := trace.SpanFromContext(ctx)
+				span.RecordError(fmt.Errorf("panic: %s", panicValue), trace.WithAttributes(
+					attribute.String("exception.stacktrace", stack),
+					attribute.String("panic.site", site),
+				))
+				span.SetStatus(codes.Error, "panic")
+
+				logs.WithContext(ctx).Error("Recovered from panic", append(redactedRequestDump(c, redactor),
+					zap.String("error", panicValue),
+					zap.String("panicSite", site),
+					zap.String("stack", stack),
+				)...)
+
+				var traceId string
+				if sc := span.SpanContext(); sc.IsValid() {
+					traceId = sc.TraceID().String()
+				}
+				alerter.alert(ctx, site, c.Request.Method, c.FullPath(), err, traceId)
 				request.SendServiceError(c, request.CreateInternalServerError(nil))
 			}
 		}()
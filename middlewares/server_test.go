@@ -0,0 +1,74 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Faze-Technologies/go-utils/logs"
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// serveLogged runs req through GinLogger on r inside a recorded span and
+// returns the span once the request has completed.
+func serveLogged(t *testing.T, register func(r *gin.Engine), req *http.Request) (sdktrace.ReadOnlySpan, *httptest.ResponseRecorder) {
+	t.Helper()
+	recorder := tracetest.NewSpanRecorder()
+	tracer := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)).Tracer("test")
+
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		ctx, span := tracer.Start(c.Request.Context(), "request")
+		defer span.End()
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	}, GinLogger(logs.NewLogger()))
+	register(r)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	spans := recorder.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("recorded %d spans, want 1", len(spans))
+	}
+	return spans[0], w
+}
+
+// spanValue returns the value of key among the span's attributes and the
+// attributes of its request.payload event.
+func spanValue(span sdktrace.ReadOnlySpan, key attribute.Key) (string, bool) {
+	attrs := span.Attributes()
+	for _, e := range span.Events() {
+		if e.Name == "request.payload" {
+			attrs = append(attrs, e.Attributes...)
+		}
+	}
+	for _, kv := range attrs {
+		if kv.Key == key {
+			return kv.Value.Emit(), true
+		}
+	}
+	return "", false
+}
+
+func TestGinLoggerRedactsPath(t *testing.T) {
+	span, _ := serveLogged(t, func(r *gin.Engine) {
+		r.GET("/reset/:token/users/:id", func(c *gin.Context) { c.Status(http.StatusNotFound) })
+	}, httptest.NewRequest(http.MethodGet, "/reset/s3cret/users/a@b.com", nil))
+
+	path, _ := spanValue(span, "http.path")
+	if want := "/reset/[REDACTED]/users/[REDACTED]"; path != want {
+		t.Errorf("http.path = %q, want %q", path, want)
+	}
+	params, ok := spanValue(span, "http.request.params")
+	if !ok {
+		t.Fatal("http.request.params not recorded")
+	}
+	if strings.Contains(params, "s3cret") || strings.Contains(params, "a@b.com") {
+		t.Errorf("http.request.params = %s, want values redacted", params)
+	}
+}
@@ -0,0 +1,213 @@
+package middlewares
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/Faze-Technologies/go-utils/deadline"
+	"github.com/Faze-Technologies/go-utils/logs"
+	"github.com/Faze-Technologies/go-utils/response"
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+// ErrHandlerTimeout is returned to a handler that writes after its route's
+// timeout has already produced the 504.
+var ErrHandlerTimeout = errors.New("http: handler wrote after timeout")
+
+// Timeout bounds a route group to d:
+//
+//	v1 := r.Group("/v1", middlewares.Timeout(3*time.Second))
+//
+// The request context gets a deadline of d, or less if the caller sent a
+// smaller budget in deadline.Header. When it fires, a 504 GATEWAY_TIMEOUT
+// ServiceError is written and flushed to the client from the timer
+// goroutine; anything the handler writes after that is discarded. The
+// middleware still waits for the handler to return, which it does promptly
+// as long as it honours the context. The handler's response is buffered
+// until then, so do not use Timeout on streaming or SSE routes.
+//
+// If the handler panics, its buffered response is dropped and the panic is
+// re-raised for the recovery middleware, which must be registered before
+// Timeout.
+func Timeout(d time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		budget := d
+		if inbound, ok := deadline.FromHeader(c.GetHeader(deadline.Header)); ok && inbound < budget {
+			budget = inbound
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), budget)
+		defer cancel()
+		c.Request = c.Request.WithContext(ctx)
+
+		original := c.Writer
+		tw := &timeoutWriter{ResponseWriter: original, header: http.Header{}, status: http.StatusOK}
+		c.Writer = tw
+
+		route := c.FullPath()
+		timer := time.AfterFunc(budget, func() { tw.timeout(ctx, route, budget) })
+		defer func() {
+			timer.Stop()
+			p := recover()
+			if p == nil {
+				tw.finish()
+				c.Writer = original
+				return
+			}
+			// Drop whatever the handler buffered and let the recovery
+			// middleware answer on the real writer. If the 504 has already
+			// gone out, leave tw in place so its writes are discarded.
+			if !tw.abandon() {
+				c.Writer = original
+			}
+			panic(p)
+		}()
+		c.Next()
+	}
+}
+
+// timeoutWriter buffers the handler's response so the timeout can write a
+// 504 from another goroutine without racing the handler, and so late writes
+// can be dropped.
+type timeoutWriter struct {
+	gin.ResponseWriter
+
+	mu        sync.Mutex
+	header    http.Header
+	body      bytes.Buffer
+	status    int
+	committed bool
+	timedOut  bool
+	done      bool
+}
+
+func (w *timeoutWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *timeoutWriter) WriteHeader(code int) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.timedOut || w.committed {
+		return
+	}
+	w.status = code
+}
+
+func (w *timeoutWriter) WriteHeaderNow() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.committed = true
+}
+
+func (w *timeoutWriter) Write(b []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.timedOut {
+		return 0, ErrHandlerTimeout
+	}
+	w.committed = true
+	return w.body.Write(b)
+}
+
+func (w *timeoutWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+func (w *timeoutWriter) Status() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.timedOut {
+		return w.ResponseWriter.Status()
+	}
+	return w.status
+}
+
+func (w *timeoutWriter) Size() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.timedOut {
+		return w.ResponseWriter.Size()
+	}
+	if !w.committed {
+		return -1
+	}
+	return w.body.Len()
+}
+
+func (w *timeoutWriter) Written() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.timedOut || w.committed
+}
+
+// Flush is a no-op: the response is only sent once the handler returns.
+func (w *timeoutWriter) Flush() {}
+
+// timeout runs on the timer goroutine. It only touches the real writer,
+// which the handler never sees, and does nothing once the handler is done.
+func (w *timeoutWriter) timeout(ctx context.Context, route string, budget time.Duration) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.done {
+		return
+	}
+	w.timedOut = true
+
+	trace.SpanFromContext(ctx).SetAttributes(attribute.Bool("http.timed_out", true))
+	logs.WithContext(ctx).Warn("Request timed out",
+		zap.String("route", route),
+		zap.Duration("budget", budget),
+	)
+	response.WriteHTTPError(w.ResponseWriter, response.GatewayTimeout("Request timed out"))
+	flushQuietly(w.ResponseWriter)
+}
+
+// flushQuietly flushes w. gin's Flush panics when the underlying writer is
+// not an http.Flusher, which on the timer goroutine would take down the
+// process; the 504 is then sent when the handler returns instead.
+func flushQuietly(w http.Flusher) {
+	defer func() { _ = recover() }()
+	w.Flush()
+}
+
+// abandon stops the timer goroutine from writing once the handler has
+// panicked. It reports whether the 504 was already sent.
+func (w *timeoutWriter) abandon() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.done = true
+	w.body.Reset()
+	return w.timedOut
+}
+
+// finish sends the buffered response unless the timeout already answered.
+func (w *timeoutWriter) finish() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.done = true
+	if w.timedOut {
+		return
+	}
+
+	dst := w.ResponseWriter.Header()
+	for k, v := range w.header {
+		dst[k] = v
+	}
+	w.ResponseWriter.WriteHeader(w.status)
+	if !w.committed {
+		return
+	}
+	if w.body.Len() > 0 {
+		w.ResponseWriter.Write(w.body.Bytes())
+	} else {
+		w.ResponseWriter.WriteHeaderNow()
+	}
+}
@@ -0,0 +1,89 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Faze-Technologies/go-utils/deadline"
+	"github.com/Faze-Technologies/go-utils/logs"
+	"github.com/gin-gonic/gin"
+)
+
+func TestTimeout(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	logs.NewLogger()
+
+	lateWrite := make(chan error, 1)
+	r := gin.New()
+	r.Use(Timeout(50 * time.Millisecond))
+	r.GET("/fast", func(c *gin.Context) {
+		c.Header("X-Handler", "fast")
+		c.JSON(http.StatusCreated, gin.H{"ok": true})
+	})
+	r.GET("/slow", func(c *gin.Context) {
+		<-c.Request.Context().Done()
+		_, err := c.Writer.Write([]byte("late"))
+		lateWrite <- err
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/fast", nil))
+	if w.Code != http.StatusCreated || w.Header().Get("X-Handler") != "fast" || !strings.Contains(w.Body.String(), `"ok":true`) {
+		t.Errorf("fast: got %d %v %q", w.Code, w.Header(), w.Body.String())
+	}
+
+	start := time.Now()
+	req := httptest.NewRequest(http.MethodGet, "/slow", nil)
+	req.Header.Set(deadline.Header, "10")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusGatewayTimeout || !strings.Contains(w.Body.String(), "GATEWAY_TIMEOUT") {
+		t.Errorf("slow: got %d %q, want 504 GATEWAY_TIMEOUT", w.Code, w.Body.String())
+	}
+	if elapsed := time.Since(start); elapsed > 40*time.Millisecond {
+		t.Errorf("slow: took %v, inbound budget of 10ms was not honoured", elapsed)
+	}
+	if err := <-lateWrite; err != ErrHandlerTimeout {
+		t.Errorf("late write error = %v, want ErrHandlerTimeout", err)
+	}
+	if strings.Contains(w.Body.String(), "late") {
+		t.Errorf("late write reached the client: %q", w.Body.String())
+	}
+}
+
+func TestTimeoutPanic(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	logs.NewLogger()
+
+	r := gin.New()
+	r.Use(gin.CustomRecovery(func(c *gin.Context, _ any) {
+		c.String(http.StatusInternalServerError, "recovered")
+	}))
+	r.Use(Timeout(50 * time.Millisecond))
+	r.GET("/panic", func(c *gin.Context) {
+		c.String(http.StatusOK, "partial")
+		panic("boom")
+	})
+	r.GET("/late-panic", func(c *gin.Context) {
+		<-c.Request.Context().Done()
+		time.Sleep(10 * time.Millisecond)
+		panic("boom")
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/panic", nil))
+	// Give a timer that was wrongly left armed the chance to fire.
+	time.Sleep(100 * time.Millisecond)
+	if w.Code != http.StatusInternalServerError || w.Body.String() != "recovered" {
+		t.Errorf("panic: got %d %q, want 500 \"recovered\"", w.Code, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/late-panic", nil))
+	if w.Code != http.StatusGatewayTimeout || strings.Contains(w.Body.String(), "recovered") {
+		t.Errorf("panic after timeout: got %d %q, want the 504 alone", w.Code, w.Body.String())
+	}
+}
@@ -3,6 +3,7 @@ package pubsub
 import (
 	"context"
 	"encoding/json"
+	"sync"
 
 	cloudpubsub "cloud.google.com/go/pubsub"
 	"github.com/Faze-Technologies/go-utils/config"
@@ -15,6 +16,11 @@ import (
 type PubSub struct {
 	client         *cloudpubsub.Client
 	closeReceivers context.CancelFunc
+
+	receiversMu sync.Mutex
+	// receivers holds the state of each receive loop started by
+	// StartSubscribers: nil while running, the exit error once stopped.
+	receivers map[string]error
 }
 
 func InitPubSub() *PubSub {
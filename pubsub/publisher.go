@@ -12,14 +12,23 @@ import (
 	"go.opentelemetry.io/otel/propagation"
 	"go.opentelemetry.io/otel/trace"
 
+	"github.com/Faze-Technologies/go-utils/deadline"
 	"github.com/Faze-Technologies/go-utils/logs"
+	"github.com/Faze-Technologies/go-utils/requestid"
 	"go.uber.org/zap"
 )
 
 // injectTraceContext injects the current OTel trace context into PubSub message
-// attributes so the subscriber can extract it and continue the trace.
+// attributes so the subscriber can extract it and continue the trace. The
+// request id and deadline, when present, ride along the same way.
 func injectTraceContext(ctx context.Context, attrs map[string]string) {
 	otel.GetTextMapPropagator().Inject(ctx, propagation.MapCarrier(attrs))
+	deadline.InjectAttributes(ctx, attrs)
+	if id := requestid.FromContext(ctx); id != "" {
+		if _, ok := attrs[requestid.Attribute]; !ok {
+			attrs[requestid.Attribute] = id
+		}
+	}
 }
 
 func (ps *PubSub) Publish(ctx context.Context, topicID string, payload interface{}, attrs map[string]string) (string, error) {
@@ -42,6 +51,10 @@ func (ps *PubSub) Publish(ctx context.Context, topicID string, payload interface
 		return "", fmt.Errorf("failed to marshal payload: %w", err)
 	}
 
+	if attrs == nil {
+		attrs = make(map[string]string)
+	}
+
 	var finalData []byte
 	if _, ok := attrs["queueName"]; ok {
 		outer := map[string]string{"data": string(rawBytes)}
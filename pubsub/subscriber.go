@@ -2,6 +2,7 @@ package pubsub
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"sync"
 
@@ -13,11 +14,37 @@ import (
 
 	"github.com/Faze-Technologies/go-utils/config"
 	"github.com/Faze-Technologies/go-utils/logs"
+	"github.com/Faze-Technologies/go-utils/requestid"
 	"go.uber.org/zap"
 )
 
 type HandlerFunction func(context.Context, *cloudpubsub.Message)
 
+// ErrReceiverStopped is reported by ReceiverStatus for a receive loop that
+// exited without an error, e.g. after ClosePubSub.
+var ErrReceiverStopped = errors.New("pubsub: receiver stopped")
+
+func (ps *PubSub) setReceiverState(subName string, err error) {
+	ps.receiversMu.Lock()
+	defer ps.receiversMu.Unlock()
+	if ps.receivers == nil {
+		ps.receivers = make(map[string]error)
+	}
+	ps.receivers[subName] = err
+}
+
+// ReceiverStatus reports the receive loops started by StartSubscribers, by
+// subscription name: nil while a loop is running, otherwise why it exited.
+func (ps *PubSub) ReceiverStatus() map[string]error {
+	ps.receiversMu.Lock()
+	defer ps.receiversMu.Unlock()
+	status := make(map[string]error, len(ps.receivers))
+	for name, err := range ps.receivers {
+		status[name] = err
+	}
+	return status
+}
+
 func (ps *PubSub) StartSubscribers(handlers map[string]HandlerFunction) {
 	logger := logs.GetLogger()
 	pubsubSubscribers := config.GetSlice("pubSub.subscribers")
@@ -52,6 +79,9 @@ func (ps *PubSub) StartSubscribers(handlers map[string]HandlerFunction) {
 				// subscriber span to the publisher's trace in SigNoz, showing the
 				// full async flow: HTTP request → publish → subscriber → handler.
 				propagatedCtx := otel.GetTextMapPropagator().Extract(ctx, propagation.MapCarrier(msg.Attributes))
+				if id := msg.Attributes[requestid.Attribute]; requestid.Valid(id) {
+					propagatedCtx = requestid.NewContext(propagatedCtx, id)
+				}
 
 				tracer := otel.Tracer("pubsub")
 				spanCtx, span := tracer.Start(propagatedCtx, subName,
@@ -72,9 +102,14 @@ func (ps *PubSub) StartSubscribers(handlers map[string]HandlerFunction) {
 				handler(spanCtx, msg)
 			}
 
-			if err := sub.Receive(ctx, wrappedHandler); err != nil {
+			ps.setReceiverState(subName, nil)
+			err := sub.Receive(ctx, wrappedHandler)
+			if err != nil {
 				logger.Error("Error on subscription", zap.String("subscription", subName), zap.Error(err))
+			} else {
+				err = ErrReceiverStopped
 			}
+			ps.setReceiverState(subName, err)
 		}(sub, subName, handler)
 	}
 	wg.Wait()
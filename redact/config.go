@@ -0,0 +1,60 @@
+package redact
+
+import (
+	"github.com/Faze-Technologies/go-utils/config"
+)
+
+const defaultMask = "[REDACTED]"
+
+// defaultKeys are always redacted, wherever they appear. Keys are compared
+// after normalizeKey, so "cardNumber", "card_number" and "Card-Number" all
+// match "cardnumber".
+var defaultKeys = []string{
+	"password", "passwd", "newpassword", "oldpassword",
+	"token", "accesstoken", "refreshtoken", "idtoken",
+	"otp", "pin", "mpin", "cvv", "cvc", "cardnumber", "pan",
+	"secret", "clientsecret", "privatekey", "apikey", "xapikey",
+	"authorization", "proxyauthorization", "cookie", "setcookie",
+	"xservicesignature",
+}
+
+// defaultPatterns are the built-in value patterns applied to every string.
+var defaultPatterns = []string{PatternCard, PatternEmail, PatternPhone}
+
+// Config describes what a Redactor masks. Keys and Patterns extend the
+// defaults; they never replace them.
+type Config struct {
+	// Keys are object keys (and header / query parameter names) whose values
+	// are masked at any depth, compared case- and separator-insensitively.
+	Keys []string
+	// Paths are JSONPath-style rules such as "$.user.address",
+	// "$.items[*].iban" or "$.beneficiaries[0].*". They match from the
+	// document root; use Keys for "anywhere" rules.
+	Paths []string
+	// Patterns are value rules applied to every string: either a built-in
+	// name (PatternCard, PatternEmail, PatternPhone) or a regular expression.
+	Patterns []string
+	// DisableDefaultPatterns turns off the built-in value patterns, for
+	// services whose payloads trip them (e.g. long numeric ids).
+	DisableDefaultPatterns bool
+	// Mask replaces redacted values. Defaults to "[REDACTED]".
+	Mask string
+}
+
+// ConfigFromService reads the redaction section of the service config:
+//
+//	redaction:
+//	  keys: [dob, aadhaar]
+//	  paths: ["$.bank.accountNumber"]
+//	  patterns: ["[A-Z]{5}[0-9]{4}[A-Z]"]
+//	  disable_default_patterns: false
+//	  mask: "***"
+func ConfigFromService() Config {
+	return Config{
+		Keys:                   config.GetSlice("redaction.keys"),
+		Paths:                  config.GetSlice("redaction.paths"),
+		Patterns:               config.GetSlice("redaction.patterns"),
+		DisableDefaultPatterns: config.GetBool("redaction.disable_default_patterns"),
+		Mask:                   config.GetString("redaction.mask"),
+	}
+}
@@ -0,0 +1,347 @@
+// Package redact masks sensitive data before it reaches logs, span events or
+// alerts. A Redactor walks nested JSON and applies three kinds of rule:
+//
+//   - key rules, matched at any depth and ignoring case and separators;
+//   - JSONPath-style path rules, matched from the document root;
+//   - value patterns (card numbers, emails, phone numbers, custom regexes),
+//     applied to every string that is not already masked by a key or path.
+//
+// The same rules apply to query strings and headers so a secret is masked
+// the same way wherever it is captured.
+package redact
+
+import (
+	"bytes"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/goccy/go-json"
+)
+
+// Redactor applies a fixed set of rules. It is safe for concurrent use.
+type Redactor struct {
+	keys     map[string]bool
+	paths    [][]string
+	patterns []valueRule
+	mask     string
+
+	// pathLeaves are the final keys of path rules, which is all Partial can
+	// match on in a fragment. wildcardLeaf is set when some path rule ends
+	// in "*" or an index, which no fragment rule can honour.
+	pathLeaves   map[string]bool
+	wildcardLeaf bool
+}
+
+// New builds a Redactor from cfg on top of the default rules. Invalid path
+// or pattern rules are reported rather than silently dropped, since a typo
+// there would leak data.
+func New(cfg Config) (*Redactor, error) {
+	r := &Redactor{keys: map[string]bool{}, pathLeaves: map[string]bool{}, mask: cfg.Mask}
+	if r.mask == "" {
+		r.mask = defaultMask
+	}
+	for _, k := range defaultKeys {
+		r.keys[normalizeKey(k)] = true
+	}
+	for _, k := range cfg.Keys {
+		r.keys[normalizeKey(k)] = true
+	}
+	for _, p := range cfg.Paths {
+		segments, err := parsePath(p)
+		if err != nil {
+			return nil, err
+		}
+		r.paths = append(r.paths, segments)
+		leaf := segments[len(segments)-1]
+		if leaf == "*" || strings.HasPrefix(leaf, "[") {
+			r.wildcardLeaf = true
+		} else {
+			r.pathLeaves[leaf] = true
+		}
+	}
+
+	patterns := cfg.Patterns
+	if !cfg.DisableDefaultPatterns {
+		patterns = append(append([]string{}, defaultPatterns...), patterns...)
+	}
+	for _, p := range patterns {
+		rule, err := compilePattern(p)
+		if err != nil {
+			return nil, err
+		}
+		r.patterns = append(r.patterns, rule)
+	}
+	return r, nil
+}
+
+// Default returns a Redactor with only the built-in rules.
+func Default() *Redactor {
+	r, _ := New(Config{})
+	return r
+}
+
+// normalizeKey lowercases k and drops separators so naming conventions do
+// not defeat key rules.
+func normalizeKey(k string) string {
+	var b strings.Builder
+	b.Grow(len(k))
+	for _, ch := range strings.ToLower(k) {
+		switch ch {
+		case '_', '-', '.', ' ':
+			continue
+		}
+		b.WriteRune(ch)
+	}
+	return b.String()
+}
+
+// SensitiveKey reports whether values under key are always masked.
+func (r *Redactor) SensitiveKey(key string) bool {
+	return r.keys[normalizeKey(key)]
+}
+
+// String masks every value-pattern match inside s.
+func (r *Redactor) String(s string) string {
+	for _, rule := range r.patterns {
+		s = rule.replace(s, r.mask)
+	}
+	return s
+}
+
+// Value returns a redacted copy of v, a value as produced by decoding JSON
+// into interface{}.
+func (r *Redactor) Value(v interface{}) interface{} {
+	return r.walk(v, nil)
+}
+
+func (r *Redactor) walk(v interface{}, path []string) interface{} {
+	if len(path) > 0 && r.matchesPath(path) {
+		return r.mask
+	}
+	switch val := v.(type) {
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(val))
+		for k, child := range val {
+			if r.SensitiveKey(k) {
+				out[k] = r.mask
+				continue
+			}
+			out[k] = r.walk(child, append(path, k))
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(val))
+		for i, child := range val {
+			out[i] = r.walk(child, append(path, "["+strconv.Itoa(i)+"]"))
+		}
+		return out
+	case string:
+		return r.String(val)
+	case json.Number:
+		// Card numbers are sometimes sent as JSON numbers.
+		if s := val.String(); r.String(s) != s {
+			return r.mask
+		}
+		return val
+	default:
+		return v
+	}
+}
+
+// JSON redacts a JSON document. Anything that is not valid JSON yields an
+// error so callers can decide what to log instead.
+func (r *Redactor) JSON(body []byte) (string, error) {
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	var data interface{}
+	if err := dec.Decode(&data); err != nil {
+		return "", err
+	}
+	out, err := json.Marshal(r.Value(data))
+	if err != nil {
+		return "", err
+	}
+	return string(out), nil
+}
+
+// Body redacts a captured request or response body according to its content
+// type. Bodies that cannot be parsed are replaced by a placeholder rather
+// than logged raw.
+func (r *Redactor) Body(body []byte, contentType string) string {
+	if strings.Contains(contentType, "application/x-www-form-urlencoded") {
+		return r.Query(string(body))
+	}
+	out, err := r.JSON(body)
+	if err != nil {
+		return "[non-json body]"
+	}
+	return out
+}
+
+// Query redacts a raw query string, keeping parameter order. Parameters
+// named by a key rule are masked whole; other values go through the value
+// patterns.
+func (r *Redactor) Query(rawQuery string) string {
+	if rawQuery == "" {
+		return ""
+	}
+	parts := strings.Split(rawQuery, "&")
+	for i, part := range parts {
+		key, value, hasValue := strings.Cut(part, "=")
+		if !hasValue {
+			continue
+		}
+		name, err := url.QueryUnescape(key)
+		if err != nil {
+			name = key
+		}
+		if r.SensitiveKey(name) {
+			parts[i] = key + "=" + r.mask
+			continue
+		}
+		decoded, err := url.QueryUnescape(value)
+		if err != nil {
+			decoded = value
+		}
+		if redacted := r.String(decoded); redacted != decoded {
+			parts[i] = key + "=" + redacted
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+// Param redacts a path parameter value. Parameters named by a key rule are
+// masked whole; other values go through the value patterns.
+func (r *Redactor) Param(key, value string) string {
+	if r.SensitiveKey(key) {
+		return r.mask
+	}
+	return r.String(value)
+}
+
+// Path redacts a request path matched by route, a pattern such as
+// "/users/:id/files/*name". Segments bound to a parameter named by a key rule
+// are masked whole; the rest of the path goes through the value patterns.
+// An empty route (no match) only applies the value patterns.
+func (r *Redactor) Path(path, route string) string {
+	segments := strings.Split(path, "/")
+	for i, seg := range strings.Split(route, "/") {
+		if i >= len(segments) || seg == "" {
+			continue
+		}
+		switch seg[0] {
+		case ':':
+			if r.SensitiveKey(seg[1:]) {
+				segments[i] = r.mask
+			}
+		case '*':
+			if r.SensitiveKey(seg[1:]) {
+				segments = append(segments[:i], r.mask)
+			}
+		}
+	}
+	return r.String(strings.Join(segments, "/"))
+}
+
+// Headers returns a redacted copy of h.
+func (r *Redactor) Headers(h http.Header) http.Header {
+	out := make(http.Header, len(h))
+	for name, values := range h {
+		redacted := make([]string, len(values))
+		for i, v := range values {
+			if r.SensitiveKey(name) {
+				redacted[i] = r.mask
+			} else {
+				redacted[i] = r.String(v)
+			}
+		}
+		out[name] = redacted
+	}
+	return out
+}
+
+// HeadersJSON renders Headers(h) as a flat JSON object for span events.
+func (r *Redactor) HeadersJSON(h http.Header) string {
+	flat := make(map[string]string, len(h))
+	for name, values := range r.Headers(h) {
+		flat[name] = strings.Join(values, ", ")
+	}
+	out, _ := json.Marshal(flat)
+	return string(out)
+}
+
+// valueRule masks matches of re for which valid (when set) returns true.
+type valueRule struct {
+	re    *regexp.Regexp
+	valid func(string) bool
+}
+
+func (v valueRule) replace(s, mask string) string {
+	if v.valid == nil {
+		return v.re.ReplaceAllString(s, mask)
+	}
+	return v.re.ReplaceAllStringFunc(s, func(m string) string {
+		if v.valid(m) {
+			return mask
+		}
+		return m
+	})
+}
+
+// jsonPairPattern finds "key": value pairs in text that is not a complete
+// JSON document, such as a truncated body.
+var jsonPairPattern = regexp.MustCompile(`"([^"\\]+)"\s*:\s*("(?:[^"\\]|\\.)*"?|[^,{}\[\]\s]+)`)
+
+// jsonContainerPattern finds keys whose value is an object or array.
+var jsonContainerPattern = regexp.MustCompile(`"([^"\\]+)"\s*:\s*[{\[]`)
+
+// Partial redacts a fragment of JSON that cannot be parsed, typically a body
+// cut off at a capture limit. Values of sensitive keys are masked wherever
+// the key and value are both visible, then value patterns are applied.
+//
+// Path rules cannot be anchored to the root in a fragment, so their final
+// key is treated like a key rule. An object or array under such a key may
+// run to the end of the fragment and is masked together with everything
+// after it.
+func (r *Redactor) Partial(fragment string) string {
+	for _, m := range jsonContainerPattern.FindAllStringSubmatchIndex(fragment, -1) {
+		if r.pathLeaves[normalizeKey(fragment[m[2]:m[3]])] {
+			fragment = fragment[:m[1]-1] + `"` + r.mask + `"`
+			break
+		}
+	}
+	fragment = jsonPairPattern.ReplaceAllStringFunc(fragment, func(pair string) string {
+		m := jsonPairPattern.FindStringSubmatch(pair)
+		if !r.SensitiveKey(m[1]) && !r.pathLeaves[normalizeKey(m[1])] {
+			return pair
+		}
+		return `"` + m[1] + `":"` + r.mask + `"`
+	})
+	return r.String(fragment)
+}
+
+// Truncated redacts a body cut off at a capture limit according to its
+// content type. Form bodies lose their last, possibly incomplete, pair and
+// are masked like a query string; JSON goes through Partial. Anything else,
+// and JSON when a path rule ends in a wildcard, is replaced by a
+// placeholder since its fields cannot be told apart.
+func (r *Redactor) Truncated(body []byte, contentType string) string {
+	switch {
+	case strings.Contains(contentType, "application/x-www-form-urlencoded"):
+		fragment := string(body)
+		end := strings.LastIndexByte(fragment, '&')
+		if end < 0 {
+			return ""
+		}
+		return r.Query(fragment[:end])
+	case strings.Contains(contentType, "json") && !r.wildcardLeaf:
+		return r.Partial(string(body))
+	case strings.Contains(contentType, "json"):
+		return "[truncated json body]"
+	default:
+		return "[non-json body]"
+	}
+}
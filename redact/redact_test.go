@@ -0,0 +1,134 @@
+package redact
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestJSON(t *testing.T) {
+	r, err := New(Config{Paths: []string{"$.beneficiaries[*].ifsc", "$.profile.*"}})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	for _, tt := range []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"camel case key", `{"cardNumber":"x","name":"a"}`, `{"cardNumber":"[REDACTED]","name":"a"}`},
+		{"snake case key", `{"private_key":"x"}`, `{"private_key":"[REDACTED]"}`},
+		{"nested key", `{"user":{"auth":{"Password":"x"}}}`, `{"user":{"auth":{"Password":"[REDACTED]"}}}`},
+		{"key in array", `[{"otp":"1234"},{"id":1}]`, `[{"otp":"[REDACTED]"},{"id":1}]`},
+		{"path with index wildcard", `{"beneficiaries":[{"ifsc":"HDFC0001","name":"a"}]}`, `{"beneficiaries":[{"ifsc":"[REDACTED]","name":"a"}]}`},
+		{"path with key wildcard", `{"profile":{"dob":"2000-01-01"},"id":"p"}`, `{"id":"p","profile":{"dob":"[REDACTED]"}}`},
+		{"card value", `{"note":"paid with 4111 1111 1111 1111"}`, `{"note":"paid with [REDACTED]"}`},
+		{"card as number", `{"n":4111111111111111}`, `{"n":"[REDACTED]"}`},
+		{"non-luhn number kept", `{"ts":1700000000000,"id":"1234567890123"}`, `{"id":"1234567890123","ts":1700000000000}`},
+		{"email value", `{"msg":"contact a.b@example.com"}`, `{"msg":"contact [REDACTED]"}`},
+		{"phone value", `{"to":"+91 9876543210","alt":"9876543210"}`, `{"alt":"[REDACTED]","to":"[REDACTED]"}`},
+	} {
+		got, err := r.JSON([]byte(tt.in))
+		if err != nil {
+			t.Errorf("%s: JSON() error = %v", tt.name, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%s: JSON() = %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestQueryAndHeaders(t *testing.T) {
+	r := Default()
+
+	if got, want := r.Query("page=2&access_token=abc&email=a%40b.com"), "page=2&access_token=[REDACTED]&email=[REDACTED]"; got != want {
+		t.Errorf("Query() = %q, want %q", got, want)
+	}
+
+	h := http.Header{}
+	h.Set("Authorization", "Bearer abc")
+	h.Set("X-Api-Key", "k")
+	h.Set("Accept", "application/json")
+	got := r.Headers(h)
+	if got.Get("Authorization") != defaultMask || got.Get("X-Api-Key") != defaultMask {
+		t.Errorf("Headers() did not mask credentials: %v", got)
+	}
+	if got.Get("Accept") != "application/json" {
+		t.Errorf("Headers() changed Accept: %v", got)
+	}
+}
+
+func TestPathAndParams(t *testing.T) {
+	r := Default()
+
+	for _, tt := range []struct {
+		name, path, route, want string
+	}{
+		{"plain", "/users/42", "/users/:id", "/users/42"},
+		{"sensitive param", "/reset/abc123/confirm", "/reset/:token/confirm", "/reset/[REDACTED]/confirm"},
+		{"sensitive catch-all", "/files/abc/def", "/files/*token", "/files/[REDACTED]"},
+		{"value pattern", "/users/a@b.com", "/users/:id", "/users/[REDACTED]"},
+		{"unmatched route", "/users/a@b.com", "", "/users/[REDACTED]"},
+	} {
+		if got := r.Path(tt.path, tt.route); got != tt.want {
+			t.Errorf("%s: Path() = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+
+	if got := r.Param("token", "abc123"); got != defaultMask {
+		t.Errorf("Param(token) = %q, want %q", got, defaultMask)
+	}
+	if got := r.Param("id", "42"); got != "42" {
+		t.Errorf("Param(id) = %q, want 42", got)
+	}
+}
+
+func TestNewRejectsBadRules(t *testing.T) {
+	for _, cfg := range []Config{
+		{Paths: []string{"user.password"}},
+		{Paths: []string{"$.items[0"}},
+		{Patterns: []string{"("}},
+	} {
+		if _, err := New(cfg); err == nil {
+			t.Errorf("New(%+v) error = nil, want error", cfg)
+		}
+	}
+}
+
+func TestPartial(t *testing.T) {
+	got := Default().Partial(`{"user":{"password":"hunter2","name":"a"},"token":"abc`)
+	want := `{"user":{"password":"[REDACTED]","name":"a"},"token":"[REDACTED]"`
+	if got != want {
+		t.Errorf("Partial() = %s, want %s", got, want)
+	}
+}
+
+func TestTruncated(t *testing.T) {
+	r, err := New(Config{Paths: []string{"$.bank.accountNumber", "$.user.address"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name, contentType, body, want string
+	}{
+		{"form", "application/x-www-form-urlencoded", "user=a&otp=123456&pin=12", "user=a&otp=[REDACTED]"},
+		{"form single pair", "application/x-www-form-urlencoded", "password=hunt", ""},
+		{"json path leaf", "application/json", `{"bank":{"accountNumber":"0012345678","ifsc":"HDFC`, `{"bank":{"accountNumber":"[REDACTED]","ifsc":"HDFC`},
+		{"json path container", "application/json", `{"name":"a","user":{"address":{"line1":"12 Main St"},"age":3`, `{"name":"a","user":{"address":"[REDACTED]"`},
+		{"unknown", "text/plain", "otp 123456 and so", "[non-json body]"},
+	}
+	for _, tt := range tests {
+		if got := r.Truncated([]byte(tt.body), tt.contentType); got != tt.want {
+			t.Errorf("%s: Truncated() = %s, want %s", tt.name, got, tt.want)
+		}
+	}
+
+	wildcard, err := New(Config{Paths: []string{"$.beneficiaries[0].*"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := wildcard.Truncated([]byte(`{"beneficiaries":[{"iban":"DE89`), "application/json"); got != "[truncated json body]" {
+		t.Errorf("Truncated() with wildcard path = %s", got)
+	}
+}
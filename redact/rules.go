@@ -0,0 +1,139 @@
+package redact
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Built-in value pattern names accepted in Config.Patterns.
+const (
+	PatternCard  = "card"
+	PatternEmail = "email"
+	PatternPhone = "phone"
+)
+
+var (
+	cardPattern  = regexp.MustCompile(`\b\d(?:[ -]?\d){12,18}\b`)
+	emailPattern = regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`)
+	// International numbers need a leading "+"; bare ten-digit numbers are
+	// only treated as phones in the Indian mobile range so unix timestamps
+	// and numeric ids are left alone.
+	phonePattern = regexp.MustCompile(`\+\d{1,3}[ -]?\d{6,14}\b|\b[6-9]\d{9}\b`)
+)
+
+func compilePattern(p string) (valueRule, error) {
+	switch p {
+	case PatternCard:
+		return valueRule{re: cardPattern, valid: luhnValid}, nil
+	case PatternEmail:
+		return valueRule{re: emailPattern}, nil
+	case PatternPhone:
+		return valueRule{re: phonePattern}, nil
+	}
+	re, err := regexp.Compile(p)
+	if err != nil {
+		return valueRule{}, fmt.Errorf("redact: invalid pattern %q: %w", p, err)
+	}
+	return valueRule{re: re}, nil
+}
+
+// luhnValid reports whether the digits in s pass the Luhn checksum, which
+// keeps the card pattern from masking arbitrary long numbers.
+func luhnValid(s string) bool {
+	sum, n := 0, 0
+	for i := len(s) - 1; i >= 0; i-- {
+		ch := s[i]
+		if ch < '0' || ch > '9' {
+			continue
+		}
+		d := int(ch - '0')
+		if n%2 == 1 {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		n++
+	}
+	return n >= 13 && sum%10 == 0
+}
+
+// parsePath splits a rule like "$.items[*].card" into the segments
+// ["items", "[*]", "card"]. Object keys are normalized like key rules; "*"
+// matches any key and "[*]" any array index.
+func parsePath(rule string) ([]string, error) {
+	p := strings.TrimSpace(rule)
+	if !strings.HasPrefix(p, "$") {
+		return nil, fmt.Errorf("redact: path %q must start with $", rule)
+	}
+	p = p[1:]
+
+	var segments []string
+	for p != "" {
+		switch p[0] {
+		case '.':
+			p = p[1:]
+			end := strings.IndexAny(p, ".[")
+			if end < 0 {
+				end = len(p)
+			}
+			if end == 0 {
+				return nil, fmt.Errorf("redact: empty segment in path %q", rule)
+			}
+			key := p[:end]
+			if key != "*" {
+				key = normalizeKey(key)
+			}
+			segments = append(segments, key)
+			p = p[end:]
+		case '[':
+			end := strings.IndexByte(p, ']')
+			if end < 0 {
+				return nil, fmt.Errorf("redact: unterminated index in path %q", rule)
+			}
+			segments = append(segments, p[:end+1])
+			p = p[end+1:]
+		default:
+			return nil, fmt.Errorf("redact: unexpected %q in path %q", p[0], rule)
+		}
+	}
+	if len(segments) == 0 {
+		return nil, fmt.Errorf("redact: path %q selects the whole document", rule)
+	}
+	return segments, nil
+}
+
+func (r *Redactor) matchesPath(path []string) bool {
+	for _, rule := range r.paths {
+		if len(rule) != len(path) {
+			continue
+		}
+		matched := true
+		for i, seg := range rule {
+			if !segmentMatches(seg, path[i]) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return true
+		}
+	}
+	return false
+}
+
+func segmentMatches(rule, actual string) bool {
+	isIndex := strings.HasPrefix(actual, "[")
+	switch {
+	case rule == "[*]":
+		return isIndex
+	case rule == "*":
+		return !isIndex
+	case isIndex:
+		return rule == actual
+	default:
+		return rule == normalizeKey(actual)
+	}
+}
@@ -24,4 +24,5 @@ const (
 	DataLossError           ErrorCode = "dataLossError"
 	MFARequiredError        ErrorCode = "mfaRequiredError"
 	VerificationError       ErrorCode = "verificationRequiredError"
+	ForceUpgradeError       ErrorCode = "forceUpgradeError"
 )
@@ -76,6 +76,14 @@ func CreateMFAError(err error, data interface{}) *ServiceError {
 	return &sErr
 }
 
+func CreateForceUpgradeError(err error, data interface{}) *ServiceError {
+	sErr := ServiceError{}
+	statusCode := http.StatusUpgradeRequired
+	errorCode := ForceUpgradeError
+	sErr.generateCustomError(statusCode, errorCode, "App Upgrade Required", err, data)
+	return &sErr
+}
+
 func (e *ServiceError) generateCustomError(statusCode int, errorCode ErrorCode, message string, err error, data interface{}) {
 	e.HttpStatus = statusCode
 	if e.error != nil && e.error.Error() != "" {
@@ -0,0 +1,79 @@
+// Package requestid carries a per-request correlation id across services.
+// The id arrives in (or is generated for) the X-Request-Id header, travels
+// in the context, and is attached to logs, spans, outbound HTTP and gRPC
+// calls and Pub/Sub messages so a single user complaint can be followed
+// end to end.
+package requestid
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+
+	"github.com/go-resty/resty/v2"
+)
+
+const (
+	// Header is the HTTP header, and lowercased the gRPC metadata key, that
+	// carries the id.
+	Header = "X-Request-Id"
+	// Attribute is the Pub/Sub message attribute that carries the id.
+	Attribute = "requestId"
+	// maxLength bounds ids accepted from callers.
+	maxLength = 128
+)
+
+type contextKey struct{}
+
+// NewContext returns a copy of ctx carrying id.
+func NewContext(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, contextKey{}, id)
+}
+
+// FromContext returns the request id in ctx, or "" if there is none.
+func FromContext(ctx context.Context) string {
+	id, _ := ctx.Value(contextKey{}).(string)
+	return id
+}
+
+// New generates a random id.
+func New() string {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(b[:])
+}
+
+// Valid reports whether an id received from a caller is safe to adopt: non
+// empty, bounded, and limited to characters that cannot break log lines or
+// headers.
+func Valid(id string) bool {
+	if id == "" || len(id) > maxLength {
+		return false
+	}
+	for _, ch := range id {
+		switch {
+		case ch >= 'a' && ch <= 'z', ch >= 'A' && ch <= 'Z', ch >= '0' && ch <= '9':
+		case ch == '-', ch == '_', ch == '.', ch == ':':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// AttachToResty registers an OnBeforeRequest hook that forwards the request
+// id from the request context on every outbound call. Like
+// geoip.AttachToResty it relies on callers using .R().SetContext(ctx).
+func AttachToResty(client *resty.Client) {
+	if client == nil {
+		return
+	}
+	client.OnBeforeRequest(func(_ *resty.Client, r *resty.Request) error {
+		if id := FromContext(r.Context()); id != "" && r.Header.Get(Header) == "" {
+			r.SetHeader(Header, id)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,76 @@
+package requestid
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-resty/resty/v2"
+)
+
+func TestNew(t *testing.T) {
+	a, b := New(), New()
+	if len(a) != 32 || !Valid(a) {
+		t.Errorf("New() = %q, want 32 valid hex characters", a)
+	}
+	if a == b {
+		t.Errorf("New() returned %q twice", a)
+	}
+}
+
+func TestValid(t *testing.T) {
+	cases := []struct {
+		id   string
+		want bool
+	}{
+		{"4bf92f3577b34da6a3ce929d0e0e4736", true},
+		{"web-1:req_42.retry", true},
+		{"", false},
+		{strings.Repeat("a", maxLength), true},
+		{strings.Repeat("a", maxLength+1), false},
+		{"id with spaces", false},
+		{"id\nforged=log", false},
+		{"id\"quote", false},
+		{"idé", false},
+	}
+	for _, tc := range cases {
+		if got := Valid(tc.id); got != tc.want {
+			t.Errorf("Valid(%q) = %v, want %v", tc.id, got, tc.want)
+		}
+	}
+}
+
+func TestAttachToResty(t *testing.T) {
+	var got string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Get(Header)
+	}))
+	defer srv.Close()
+
+	client := resty.New()
+	AttachToResty(client)
+
+	ctx := NewContext(context.Background(), "req-1")
+	if _, err := client.R().SetContext(ctx).Get(srv.URL); err != nil {
+		t.Fatal(err)
+	}
+	if got != "req-1" {
+		t.Errorf("forwarded %s = %q, want req-1", Header, got)
+	}
+
+	if _, err := client.R().SetContext(ctx).SetHeader(Header, "explicit").Get(srv.URL); err != nil {
+		t.Fatal(err)
+	}
+	if got != "explicit" {
+		t.Errorf("%s = %q, want the explicitly set id kept", Header, got)
+	}
+
+	if _, err := client.R().SetContext(context.Background()).Get(srv.URL); err != nil {
+		t.Fatal(err)
+	}
+	if got != "" {
+		t.Errorf("%s = %q sent without an id in context", Header, got)
+	}
+}
@@ -53,6 +53,12 @@ func (e *ServiceError) ToHTTPStatus() int {
 		return http.StatusPreconditionRequired
 	case CodeVerificationRequired:
 		return http.StatusPreconditionRequired
+	case CodeGatewayTimeout:
+		return http.StatusGatewayTimeout
+	case CodePayloadTooLarge:
+		return http.StatusRequestEntityTooLarge
+	case CodeUnsupportedMediaType:
+		return http.StatusUnsupportedMediaType
 	default:
 		return http.StatusInternalServerError
 	}
@@ -114,6 +120,12 @@ func (e *ServiceError) toGRPCCode() codes.Code {
 		return codes.FailedPrecondition
 	case CodeVerificationRequired:
 		return codes.FailedPrecondition
+	case CodeGatewayTimeout:
+		return codes.DeadlineExceeded
+	case CodePayloadTooLarge:
+		return codes.ResourceExhausted
+	case CodeUnsupportedMediaType:
+		return codes.InvalidArgument
 	default:
 		return codes.Unknown
 	}
@@ -32,6 +32,9 @@ const (
 	CodeBusinessRuleViolation ErrorCode = "BUSINESS_RULE_VIOLATION"
 	CodeMFARequired           ErrorCode = "MFA_REQUIRED"
 	CodeVerificationRequired  ErrorCode = "VERIFICATION_REQUIRED"
+	CodeGatewayTimeout        ErrorCode = "GATEWAY_TIMEOUT"
+	CodePayloadTooLarge       ErrorCode = "PAYLOAD_TOO_LARGE"
+	CodeUnsupportedMediaType  ErrorCode = "UNSUPPORTED_MEDIA_TYPE"
 )
 
 // ServiceError represents a standardized error that can be converted to HTTP or gRPC
@@ -215,3 +218,23 @@ func MFARequired(message string) *ServiceError {
 func VerificationRequired(message string) *ServiceError {
 	return New(CodeVerificationRequired, message)
 }
+
+// GatewayTimeout creates an error for a request that ran out of time budget
+func GatewayTimeout(message string) *ServiceError {
+	return New(CodeGatewayTimeout, message)
+}
+
+// Unavailable creates an error for a request the service cannot take right now
+func Unavailable(message string) *ServiceError {
+	return New(CodeUnavailable, message)
+}
+
+// PayloadTooLarge creates an error for a request body over the size limit
+func PayloadTooLarge(message string) *ServiceError {
+	return New(CodePayloadTooLarge, message)
+}
+
+// UnsupportedMediaType creates an error for a request body in an unsupported format or encoding
+func UnsupportedMediaType(message string) *ServiceError {
+	return New(CodeUnsupportedMediaType, message)
+}
@@ -0,0 +1,50 @@
+package revocation
+
+import (
+	"sync"
+	"time"
+)
+
+// maxLocalEntries bounds memory; when reached the cache is simply cleared,
+// which at worst costs one extra Redis round trip per active user.
+const maxLocalEntries = 100_000
+
+type localEntry struct {
+	value     string
+	expiresAt time.Time
+}
+
+type localCache struct {
+	ttl     time.Duration
+	mu      sync.RWMutex
+	entries map[string]localEntry
+}
+
+func newLocalCache(ttl time.Duration) *localCache {
+	return &localCache{ttl: ttl, entries: make(map[string]localEntry)}
+}
+
+func (l *localCache) get(key string) (string, bool) {
+	l.mu.RLock()
+	e, ok := l.entries[key]
+	l.mu.RUnlock()
+	if !ok || time.Now().After(e.expiresAt) {
+		return "", false
+	}
+	return e.value, true
+}
+
+func (l *localCache) set(key, value string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if len(l.entries) >= maxLocalEntries {
+		l.entries = make(map[string]localEntry)
+	}
+	l.entries[key] = localEntry{value: value, expiresAt: time.Now().Add(l.ttl)}
+}
+
+func (l *localCache) delete(key string) {
+	l.mu.Lock()
+	delete(l.entries, key)
+	l.mu.Unlock()
+}
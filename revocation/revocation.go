@@ -0,0 +1,160 @@
+// Package revocation invalidates access tokens before they expire. It keeps
+// two kinds of record in Redis:
+//
+//   - a denylist of token IDs (jti), each kept only for the token's remaining
+//     lifetime, for revoking a single session;
+//   - a per-user watermark: every token for that user issued before the
+//     watermark is invalid. Used for forced logout after a password change or
+//     account ban.
+//
+// Lookups go through a short-lived in-process cache so an authenticated
+// request costs at most one Redis round trip per cache window. A revocation
+// therefore takes up to the local TTL to reach every pod.
+//
+// middlewares.InitializeMiddlewares only wires a Store into AuthenticateUser
+// when auth.revocation.enabled is true.
+package revocation
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/Faze-Technologies/go-utils/cache"
+	"github.com/Faze-Technologies/go-utils/config"
+)
+
+const (
+	defaultLocalTTL = 10 * time.Second
+	// defaultWatermarkTTL must be at least the longest access token lifetime;
+	// once every older token has expired the watermark is moot.
+	defaultWatermarkTTL = 30 * 24 * time.Hour
+)
+
+// revocationCache is the Redis surface the Store uses; *cache.Cache
+// satisfies it.
+type revocationCache interface {
+	Set(ctx context.Context, key string, value string, expiration time.Duration) error
+	GetMultiKeys(ctx context.Context, keys []string) (map[string]string, error)
+}
+
+// Store is the package handle returned by NewStore.
+type Store struct {
+	cache        revocationCache
+	local        *localCache
+	watermarkTTL time.Duration
+}
+
+// NewStore builds a Store. localTTL <= 0 falls back to
+// auth.revocation.local_ttl_ms and then to the package default.
+func NewStore(c *cache.Cache, localTTL time.Duration) *Store {
+	if localTTL <= 0 {
+		localTTL = defaultLocalTTL
+		if ms := config.GetInt("auth.revocation.local_ttl_ms"); ms > 0 {
+			localTTL = time.Duration(ms) * time.Millisecond
+		}
+	}
+	watermarkTTL := defaultWatermarkTTL
+	if s := config.GetInt("auth.revocation.watermark_ttl_seconds"); s > 0 {
+		watermarkTTL = time.Duration(s) * time.Second
+	}
+	return &Store{cache: c, local: newLocalCache(localTTL), watermarkTTL: watermarkTTL}
+}
+
+func tokenKey(jti string) string {
+	return fmt.Sprintf("auth:revoked:jti:%s", jti)
+}
+
+func userKey(userId string) string {
+	return fmt.Sprintf("auth:revoked:user:%s", userId)
+}
+
+// RevokeToken denylists a single token until it would have expired anyway.
+func (s *Store) RevokeToken(ctx context.Context, jti string, expiresAt time.Time) error {
+	remaining := time.Until(expiresAt)
+	if remaining <= 0 {
+		return nil
+	}
+	if err := s.cache.Set(ctx, tokenKey(jti), "1", remaining); err != nil {
+		return fmt.Errorf("revocation: denylist token: %w", err)
+	}
+	s.local.delete(tokenKey(jti))
+	return nil
+}
+
+// RevokeUserTokens invalidates every token for userId issued before before.
+// Pass time.Now() to log the user out everywhere. iat only has second
+// resolution, so tokens issued in the same second as before are revoked too.
+func (s *Store) RevokeUserTokens(ctx context.Context, userId string, before time.Time) error {
+	value := strconv.FormatInt(before.Unix(), 10)
+	if err := s.cache.Set(ctx, userKey(userId), value, s.watermarkTTL); err != nil {
+		return fmt.Errorf("revocation: set watermark: %w", err)
+	}
+	s.local.delete(userKey(userId))
+	return nil
+}
+
+// IsRevoked reports whether the token identified by jti, issued to userId at
+// issuedAt, has been revoked. An empty jti skips the denylist and a zero
+// issuedAt is treated as older than any watermark.
+func (s *Store) IsRevoked(ctx context.Context, jti string, userId string, issuedAt time.Time) (bool, error) {
+	var keys []string
+	if jti != "" {
+		keys = append(keys, tokenKey(jti))
+	}
+	if userId != "" {
+		keys = append(keys, userKey(userId))
+	}
+	if len(keys) == 0 {
+		return false, nil
+	}
+
+	values, err := s.get(ctx, keys)
+	if err != nil {
+		return false, err
+	}
+
+	if jti != "" && values[tokenKey(jti)] != "" {
+		return true, nil
+	}
+	if raw := values[userKey(userId)]; userId != "" && raw != "" {
+		watermark, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return false, fmt.Errorf("revocation: bad watermark for %s: %w", userId, err)
+		}
+		if issuedAt.IsZero() || issuedAt.Unix() <= watermark {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// get resolves keys from the local cache, fetching any misses from Redis in
+// a single MGET. Absent keys are cached locally as "" so the common
+// not-revoked case stays off Redis too.
+func (s *Store) get(ctx context.Context, keys []string) (map[string]string, error) {
+	out := make(map[string]string, len(keys))
+	var misses []string
+	for _, k := range keys {
+		if v, ok := s.local.get(k); ok {
+			out[k] = v
+		} else {
+			misses = append(misses, k)
+		}
+	}
+	if len(misses) == 0 {
+		return out, nil
+	}
+
+	fetched, err := s.cache.GetMultiKeys(ctx, misses)
+	if err != nil {
+		return nil, fmt.Errorf("revocation: lookup: %w", err)
+	}
+	for _, k := range misses {
+		v := fetched[k]
+		s.local.set(k, v)
+		out[k] = v
+	}
+	return out, nil
+}
@@ -0,0 +1,123 @@
+package revocation
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+// memoryCache is an in-memory revocationCache shared by the Stores of
+// simulated pods. Expiry is ignored.
+type memoryCache struct {
+	mu     sync.Mutex
+	values map[string]string
+	reads  int
+}
+
+func (m *memoryCache) Set(_ context.Context, key string, value string, _ time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.values[key] = value
+	return nil
+}
+
+func (m *memoryCache) GetMultiKeys(_ context.Context, keys []string) (map[string]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.reads++
+	out := make(map[string]string, len(keys))
+	for _, k := range keys {
+		if v, ok := m.values[k]; ok {
+			out[k] = v
+		}
+	}
+	return out, nil
+}
+
+func newTestStore(shared *memoryCache, localTTL time.Duration) *Store {
+	s := NewStore(nil, localTTL)
+	s.cache = shared
+	return s
+}
+
+func TestRevokeToken(t *testing.T) {
+	s := newTestStore(&memoryCache{values: map[string]string{}}, time.Minute)
+	ctx := context.Background()
+	issued := time.Now().Add(-time.Minute)
+
+	if revoked, err := s.IsRevoked(ctx, "jti-1", "u1", issued); err != nil || revoked {
+		t.Fatalf("IsRevoked() = %v, %v before revocation", revoked, err)
+	}
+	if err := s.RevokeToken(ctx, "jti-1", time.Now().Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if revoked, _ := s.IsRevoked(ctx, "jti-1", "u1", issued); !revoked {
+		t.Error("revoked token still accepted on the revoking pod")
+	}
+	if revoked, _ := s.IsRevoked(ctx, "jti-2", "u1", issued); revoked {
+		t.Error("revoking one token revoked another")
+	}
+
+	if err := s.RevokeToken(ctx, "jti-3", time.Now().Add(-time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	if revoked, _ := s.IsRevoked(ctx, "jti-3", "u1", issued); revoked {
+		t.Error("already expired token was denylisted")
+	}
+}
+
+func TestUserWatermark(t *testing.T) {
+	s := newTestStore(&memoryCache{values: map[string]string{}}, time.Minute)
+	ctx := context.Background()
+	now := time.Now()
+
+	if err := s.RevokeUserTokens(ctx, "u1", now); err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		name     string
+		userId   string
+		issuedAt time.Time
+		want     bool
+	}{
+		{"issued before", "u1", now.Add(-time.Hour), true},
+		{"issued after", "u1", now.Add(time.Second), false},
+		{"same second", "u1", time.Unix(now.Unix(), 999_000_000), true},
+		{"no iat", "u1", time.Time{}, true},
+		{"other user", "u2", now.Add(-time.Hour), false},
+	}
+	for _, tc := range cases {
+		if got, err := s.IsRevoked(ctx, "", tc.userId, tc.issuedAt); err != nil || got != tc.want {
+			t.Errorf("%s: IsRevoked() = %v, %v, want %v", tc.name, got, err, tc.want)
+		}
+	}
+}
+
+func TestLocalCacheExpiry(t *testing.T) {
+	shared := &memoryCache{values: map[string]string{}}
+	podA := newTestStore(shared, 50*time.Millisecond)
+	podB := newTestStore(shared, 50*time.Millisecond)
+	ctx := context.Background()
+	issued := time.Now().Add(-time.Minute)
+
+	for i := 0; i < 3; i++ {
+		if revoked, _ := podA.IsRevoked(ctx, "jti-1", "u1", issued); revoked {
+			t.Fatal("token revoked before RevokeToken")
+		}
+	}
+	if shared.reads != 1 {
+		t.Errorf("Redis read %d times, want 1 while cached locally", shared.reads)
+	}
+
+	if err := podB.RevokeToken(ctx, "jti-1", time.Now().Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if revoked, _ := podA.IsRevoked(ctx, "jti-1", "u1", issued); revoked {
+		t.Error("other pod saw the revocation before its local cache expired")
+	}
+	time.Sleep(60 * time.Millisecond)
+	if revoked, _ := podA.IsRevoked(ctx, "jti-1", "u1", issued); !revoked {
+		t.Error("other pod still accepts the token after its local cache expired")
+	}
+}
@@ -0,0 +1,131 @@
+package serviceauth
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+)
+
+const testKey = "00112233445566778899aabbccddeeff"
+
+// memoryNonces is an in-memory nonceStore.
+type memoryNonces struct {
+	mu   sync.Mutex
+	seen map[string]bool
+}
+
+func (m *memoryNonces) SetWithNX(_ context.Context, key string, _ interface{}, _ time.Duration) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.seen[key] {
+		return false, nil
+	}
+	m.seen[key] = true
+	return true, nil
+}
+
+func newTestVerifier() *Verifier {
+	return &Verifier{
+		keys:      map[string]string{"orders": testKey},
+		tolerance: time.Minute,
+		nonces:    &memoryNonces{seen: map[string]bool{}},
+	}
+}
+
+func signedRequest(t *testing.T, body string) *http.Request {
+	t.Helper()
+	r := httptest.NewRequest(http.MethodPost, "/internal/wallet/debit?currency=INR", strings.NewReader(body))
+	r.Header.Set(HeaderUserID, "64f0c2")
+	if err := NewSigner("orders", testKey).SignHTTPRequest(r); err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+func verify(v *Verifier, r *http.Request) (string, error) {
+	body, _ := io.ReadAll(r.Body)
+	return v.Verify(context.Background(), r, body)
+}
+
+func TestVerify(t *testing.T) {
+	v := newTestVerifier()
+
+	r := signedRequest(t, `{"amount":100}`)
+	if service, err := verify(v, r); err != nil || service != "orders" {
+		t.Fatalf("Verify() = %q, %v, want orders", service, err)
+	}
+
+	tests := []struct {
+		name   string
+		mutate func(r *http.Request)
+		body   string
+		want   error
+	}{
+		{"tampered body", nil, `{"amount":100000}`, ErrInvalidSignature},
+		{"tampered query", func(r *http.Request) { r.URL.RawQuery = "currency=USD" }, "", ErrInvalidSignature},
+		{"swapped user", func(r *http.Request) { r.Header.Set(HeaderUserID, "other") }, "", ErrInvalidSignature},
+		{"unsigned legacy user", func(r *http.Request) { r.Header.Set(headerLegacyUserID, "other") }, "", ErrUnsignedUserID},
+		{"skewed timestamp", func(r *http.Request) {
+			r.Header.Set(HeaderTimestamp, strconv.FormatInt(time.Now().Add(-2*time.Minute).Unix(), 10))
+		}, "", ErrStaleTimestamp},
+		{"unknown service", func(r *http.Request) { r.Header.Set(HeaderService, "billing") }, "", ErrUnknownService},
+		{"missing signature", func(r *http.Request) { r.Header.Del(HeaderSignature) }, "", ErrMissingHeaders},
+	}
+	for _, tt := range tests {
+		r := signedRequest(t, `{"amount":100}`)
+		if tt.mutate != nil {
+			tt.mutate(r)
+		}
+		if tt.body != "" {
+			r.Body = io.NopCloser(strings.NewReader(tt.body))
+		}
+		if _, err := verify(v, r); !errors.Is(err, tt.want) {
+			t.Errorf("%s: Verify() = %v, want %v", tt.name, err, tt.want)
+		}
+	}
+}
+
+func TestVerifyRejectsReplay(t *testing.T) {
+	v := newTestVerifier()
+	r := signedRequest(t, `{"amount":100}`)
+	replay := r.Clone(context.Background())
+	replay.Body = io.NopCloser(strings.NewReader(`{"amount":100}`))
+
+	if _, err := verify(v, r); err != nil {
+		t.Fatalf("first Verify() = %v", err)
+	}
+	if _, err := verify(v, replay); !errors.Is(err, ErrReplayedNonce) {
+		t.Errorf("replayed Verify() = %v, want %v", err, ErrReplayedNonce)
+	}
+}
+
+func TestAttachToResty(t *testing.T) {
+	v := newTestVerifier()
+	var verifyErr error
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, verifyErr = verify(v, r)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	client := resty.New()
+	NewSigner("orders", testKey).AttachToResty(client, func(_ *resty.Client, r *http.Request) error {
+		r.Header.Set(HeaderUserID, "64f0c2")
+		return nil
+	})
+	if _, err := client.R().SetBody(map[string]int{"amount": 100}).Post(srv.URL + "/internal/wallet/debit"); err != nil {
+		t.Fatal(err)
+	}
+	if verifyErr != nil {
+		t.Errorf("Verify() = %v for a request signed by AttachToResty", verifyErr)
+	}
+}